@@ -0,0 +1,206 @@
+package src
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	bh "github.com/birabittoh/bunnyhue"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+)
+
+const (
+	seasonFormat   = "01-02"
+	frostThreshold = 0.0
+	maxSeasons     = 5
+)
+
+// Season rappresenta la configurazione di una stagione agricola.
+type Season struct {
+	Start          string  `json:"start"` // MM-DD
+	Base           float64 `json:"base"`
+	ChillThreshold float64 `json:"chill_threshold"`
+}
+
+type AgricultureDay struct {
+	Date          string  `json:"date"`
+	TMin          float64 `json:"t_min"`
+	TMax          float64 `json:"t_max"`
+	GDD           float64 `json:"gdd"`
+	CumGDD        float64 `json:"cum_gdd"`
+	ChillHours    float64 `json:"chill_hours"`
+	CumChillHours float64 `json:"cum_chill_hours"`
+}
+
+type Agriculture struct {
+	Season
+	Unit            string           `json:"unit"`
+	Year            int              `json:"year"`
+	From            string           `json:"from"`
+	To              string           `json:"to"`
+	Days            []AgricultureDay `json:"days"`
+	TotalGDD        float64          `json:"total_gdd"`
+	TotalChillHours float64          `json:"total_chill_hours"`
+	LastFrost       string           `json:"last_frost,omitempty"`
+	FirstFrost      string           `json:"first_frost,omitempty"`
+}
+
+// getSeason legge la configurazione della stagione dalle variabili d'ambiente.
+func getSeason() (s Season, err error) {
+	s.Start = getEnvDefault("AGRI_SEASON_START", "04-01")
+	if _, err = time.Parse(seasonFormat, s.Start); err != nil {
+		err = errors.New("AGRI_SEASON_START non valida: " + s.Start)
+		return
+	}
+
+	s.Base, err = strconv.ParseFloat(getEnvDefault("AGRI_GDD_BASE", "10"), 64)
+	if err != nil {
+		err = errors.New("AGRI_GDD_BASE non valida: " + err.Error())
+		return
+	}
+
+	s.ChillThreshold, err = strconv.ParseFloat(getEnvDefault("AGRI_CHILL_THRESHOLD", "7"), 64)
+	if err != nil {
+		err = errors.New("AGRI_CHILL_THRESHOLD non valida: " + err.Error())
+	}
+	return
+}
+
+// bounds restituisce l'inizio della stagione che comincia nell'anno indicato e l'inizio della successiva.
+func (s Season) bounds(year int) (from, to time.Time) {
+	d, _ := time.Parse(seasonFormat, s.Start)
+	from = time.Date(year, d.Month(), d.Day(), 0, 0, 0, 0, appLocation)
+	to = from.AddDate(1, 0, 0)
+	return
+}
+
+// currentYear restituisce l'anno in cui è cominciata la stagione in corso.
+func (s Season) currentYear(now time.Time) int {
+	from, _ := s.bounds(now.Year())
+	if now.Before(from) {
+		return now.Year() - 1
+	}
+	return now.Year()
+}
+
+// computeAgriculture calcola gradi giorno di crescita, ore di freddo e gelate a partire da temp e temp_min.
+func computeAgriculture(dp []DataPoint, s Season, year int) (a Agriculture) {
+	from, to := s.bounds(year)
+	a = Agriculture{Season: s, Year: year, From: from.Format(dateFormat), To: to.Format(dateFormat), Days: []AgricultureDay{}}
+
+	// Le gelate di fine inverno si cercano nella prima metà della stagione, quelle autunnali nella seconda,
+	// così che la divisione segua AGRI_SEASON_START in entrambi gli emisferi.
+	midSeason := from.AddDate(0, 6, 0)
+
+	var day *AgricultureDay
+	var dayTime time.Time
+	flush := func() {
+		if day == nil {
+			return
+		}
+		day.GDD = max((day.TMin+day.TMax)/2-s.Base, 0)
+		a.TotalGDD += day.GDD
+		a.TotalChillHours += day.ChillHours
+		day.CumGDD = a.TotalGDD
+		day.CumChillHours = a.TotalChillHours
+
+		if day.TMin <= frostThreshold {
+			if dayTime.Before(midSeason) {
+				a.LastFrost = day.Date
+			} else if a.FirstFrost == "" {
+				a.FirstFrost = day.Date
+			}
+		}
+		a.Days = append(a.Days, *day)
+	}
+
+	for i, p := range dp {
+		t := localTime(int64(p.Dt))
+		d := dayStart(t)
+		if day == nil || !d.Equal(dayTime) {
+			flush()
+			dayTime = d
+			day = &AgricultureDay{Date: d.Format(dateFormat), TMin: p.Value1, TMax: p.Value0}
+		}
+		day.TMin = min(day.TMin, p.Value1)
+		day.TMax = max(day.TMax, p.Value0)
+
+		if i+1 < len(dp) {
+			dt := dp[i+1].Dt - p.Dt
+			if dt > 0 && !isGap(dt) && p.Value0 < s.ChillThreshold {
+				day.ChillHours += dt / 3600
+			}
+		}
+	}
+	flush()
+
+	return
+}
+
+// convert esprime temperature, soglie e gradi giorno nell'unità di temperatura del sistema.
+func (a *Agriculture) convert(us UnitSystem) {
+	a.Unit = us.Unit("temp")
+	a.Base, a.ChillThreshold = us.Convert("temp", a.Base), us.Convert("temp", a.ChillThreshold)
+	a.TotalGDD = us.ConvertDelta("temp", a.TotalGDD)
+	for i := range a.Days {
+		d := &a.Days[i]
+		d.TMin, d.TMax = us.Convert("temp", d.TMin), us.Convert("temp", d.TMax)
+		d.GDD, d.CumGDD = us.ConvertDelta("temp", d.GDD), us.ConvertDelta("temp", d.CumGDD)
+	}
+}
+
+func getAgriculture(s Season, year int, us UnitSystem) (a Agriculture, err error) {
+	from, to := s.bounds(year)
+	f, t := alignConstraints(from.Unix(), to.Unix()-1)
+
+	dp, err := getDataPoints([]string{"temp", "temp_min"}, f, t)
+	if err != nil {
+		err = errors.New("errore nella lettura dei dati: " + err.Error())
+		return
+	}
+	a = computeAgriculture(dp, s, year)
+	a.convert(us)
+	return
+}
+
+// plotAgriculture disegna i gradi giorno cumulati della stagione richiesta e delle precedenti,
+// allineando le stagioni passate sulle date di quella richiesta.
+func plotAgriculture(s Season, year, previous int, us UnitSystem, l *Locale, dec *decorations, palette *bh.Palette) (p *plot.Plot, err error) {
+	if previous < 0 || previous > maxSeasons {
+		err = fmt.Errorf("è possibile confrontare al massimo %d stagioni", maxSeasons)
+		return
+	}
+
+	colors := seriesColors(palette)
+	from, to := s.bounds(year)
+	p = newPlot(l, palette)
+	dec.background(p)
+	p.X.Min, p.X.Max = float64(from.Unix()), float64(to.Unix())
+
+	for i := previous; i >= 0; i-- {
+		var a Agriculture
+		a, err = getAgriculture(s, year-i, us)
+		if err != nil {
+			return
+		}
+
+		pts := make(plotter.XYs, len(a.Days))
+		for j, d := range a.Days {
+			day, _ := time.ParseInLocation(dateFormat, d.Date, appLocation)
+			pts[j].X = float64(day.AddDate(i, 0, 0).Unix())
+			pts[j].Y = d.CumGDD
+		}
+
+		err = addLines(p, pts, colors[i], i > 0, strconv.Itoa(year-i))
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func (a Agriculture) Previous() int { return a.Year - 1 }
+func (a Agriculture) Next() int     { return a.Year + 1 }
@@ -0,0 +1,179 @@
+package src
+
+import (
+	"errors"
+	"time"
+
+	bh "github.com/birabittoh/bunnyhue"
+	"github.com/hashicorp/golang-lru/v2/expirable"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+)
+
+const (
+	normalDays  = 366
+	normalHours = 24
+
+	// Semiampiezza della finestra di smoothing delle normali
+	smoothDays  = 7
+	smoothHours = 1
+)
+
+var normalsCache = expirable.NewLRU[string, *normals](64, nil, 6*time.Hour)
+
+// normals contiene i valori medi di una misura per giorno dell'anno e ora del giorno.
+type normals struct {
+	value [normalDays][normalHours]float64
+	ok    [normalDays][normalHours]bool
+}
+
+type AnomalyPoint struct {
+	Dt      int64   `json:"dt"`
+	Value   float64 `json:"value"`
+	Normal  float64 `json:"normal"`
+	Anomaly float64 `json:"anomaly"`
+
+	GapBefore bool `json:"gap_before,omitempty"` // preceduto da un buco nei dati
+}
+
+func normalIndex(t time.Time) (int, int) {
+	return min(t.YearDay(), normalDays) - 1, t.Hour()
+}
+
+// computeNormals calcola le normali su tutti gli anni disponibili, con una media pesata
+// (triangolare) sui giorni e sulle ore vicine, trattando entrambi gli assi come circolari.
+func computeNormals(dp []DataPoint) *normals {
+	var sum, count [normalDays][normalHours]float64
+	for _, p := range dp {
+		d, h := normalIndex(localTime(int64(p.Dt)))
+		sum[d][h] += p.Value0
+		count[d][h]++
+	}
+
+	n := &normals{}
+	for d := 0; d < normalDays; d++ {
+		for h := 0; h < normalHours; h++ {
+			var s, c float64
+			for dd := -smoothDays; dd <= smoothDays; dd++ {
+				for hh := -smoothHours; hh <= smoothHours; hh++ {
+					w := float64((smoothDays + 1 - abs(dd)) * (smoothHours + 1 - abs(hh)))
+					i := (d + dd + normalDays) % normalDays
+					j := (h + hh + normalHours) % normalHours
+					s += w * sum[i][j]
+					c += w * count[i][j]
+				}
+			}
+			if c > 0 {
+				n.value[d][h] = s / c
+				n.ok[d][h] = true
+			}
+		}
+	}
+	return n
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func getNormals(measure string) (n *normals, err error) {
+	if n, ok := normalsCache.Get(measure); ok {
+		return n, nil
+	}
+
+	f, t := alignConstraints(0, time.Now().Unix())
+	dp, err := getDataPoints([]string{measure}, f, t)
+	if err != nil {
+		return
+	}
+
+	n = computeNormals(dp)
+	normalsCache.Add(measure, n)
+	return
+}
+
+// getAnomaly restituisce la differenza tra i valori osservati e le normali, saltando i campioni senza normale.
+// Le normali sono calcolate in unità canoniche e convertite solo nel risultato.
+func getAnomaly(measure string, f, t *int64, us UnitSystem) (a []AnomalyPoint, err error) {
+	n, err := getNormals(measure)
+	if err != nil {
+		err = errors.New("errore nel calcolo delle normali: " + err.Error())
+		return
+	}
+
+	dp, err := getDataPoints([]string{measure}, f, t)
+	if err != nil {
+		err = errors.New("errore nella lettura dei dati: " + err.Error())
+		return
+	}
+
+	return computeAnomaly(measure, dp, n, us), nil
+}
+
+// computeAnomaly confronta i campioni con le normali. L'anomalia è la differenza tra valore e normale
+// già convertiti, perché alcune unità (es. la scala Beaufort) non sono lineari.
+func computeAnomaly(measure string, dp []DataPoint, n *normals, us UnitSystem) []AnomalyPoint {
+	a := []AnomalyPoint{}
+	for i, p := range dp {
+		d, h := normalIndex(localTime(int64(p.Dt)))
+		if !n.ok[d][h] {
+			continue
+		}
+		value, normal := us.Convert(measure, p.Value0), us.Convert(measure, n.value[d][h])
+		a = append(a, AnomalyPoint{
+			Dt:        int64(p.Dt),
+			Value:     value,
+			Normal:    normal,
+			Anomaly:   value - normal,
+			GapBefore: i > 0 && isGap(p.Dt-dp[i-1].Dt),
+		})
+	}
+	return a
+}
+
+// plotAnomaly disegna le anomalie come barre; oltre i tre giorni le anomalie vengono mediate per giorno.
+func plotAnomaly(measure string, f, t *int64, us UnitSystem, l *Locale, dec *decorations, palette *bh.Palette) (p *plot.Plot, err error) {
+	a, err := getAnomaly(measure, f, t, us)
+	if err != nil {
+		return
+	}
+
+	daily := *t-*f > 3*24*60*60
+	width := float64(cronInterval)
+	if daily {
+		width = 24 * 60 * 60
+	}
+
+	var pts plotter.XYs
+	var count float64
+	for _, v := range a {
+		x := localTime(v.Dt)
+		if daily {
+			x = dayStart(x).Add(12 * time.Hour)
+		}
+
+		if len(pts) > 0 && pts[len(pts)-1].X == float64(x.Unix()) {
+			last := &pts[len(pts)-1]
+			last.Y = (last.Y*count + v.Anomaly) / (count + 1)
+			count++
+			continue
+		}
+
+		pts = append(pts, plotter.XY{X: float64(x.Unix()), Y: v.Anomaly})
+		count = 1
+	}
+
+	p = newPlot(l, palette)
+	dec.background(p)
+	if unit := us.Unit(measure); unit != "" {
+		setAxisLabel(&p.Y, l.T("Anomaly (%s)", unit))
+	}
+
+	bars := &timeBars{XYs: pts, Width: width, Positive: palette.Red, Negative: palette.Blue}
+	p.Add(bars)
+	p.Legend.Add(l.T("%s anomaly", l.MeasureName(measure)), bars)
+	return
+}
@@ -0,0 +1,31 @@
+package src
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestComputeAnomalyBeaufort(t *testing.T) {
+	at := time.Date(2025, 3, 10, 12, 0, 0, 0, appLocation)
+	n := &normals{}
+	d, h := normalIndex(at)
+	n.value[d][h], n.ok[d][h] = 10, true
+
+	dp := []DataPoint{
+		{Dt: float64(at.Unix()), Value0: 3},
+		{Dt: float64(at.Add(24 * time.Hour).Unix()), Value0: 3}, // senza normale
+	}
+	us := UnitSystem{Name: unitsCustom, Temperature: "C", Speed: "bft", Pressure: "hPa", Precipitation: "mm", Distance: "m"}
+
+	a := computeAnomaly("wind_speed", dp, n, us)
+	if len(a) != 1 {
+		t.Fatalf("got %d points, want 1", len(a))
+	}
+	if want := beaufort(3) - beaufort(10); math.Abs(a[0].Anomaly-want) > 1e-9 || a[0].Anomaly >= 0 {
+		t.Errorf("anomaly = %v, want %v", a[0].Anomaly, want)
+	}
+	if a[0].Anomaly != a[0].Value-a[0].Normal {
+		t.Errorf("anomaly %v differs from value %v minus normal %v", a[0].Anomaly, a[0].Value, a[0].Normal)
+	}
+}
@@ -3,44 +3,51 @@ package src
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"html/template"
 	"log"
+	"maps"
 	"net/http"
 	"net/url"
-	"os"
+	"slices"
 	"strconv"
 	"sync"
 	"time"
 
 	bh "github.com/birabittoh/bunnyhue"
 	"github.com/hashicorp/golang-lru/v2/expirable"
+	"gonum.org/v1/plot"
 )
 
 const (
-	base = "base"
-	ps   = string(os.PathSeparator)
+	base         = "base"
+	templatesDir = "templates/" // percorso nelle risorse dell'applicazione, sempre con "/"
 
-	basePath    = "templates" + ps + base + ".gohtml"
-	indexPath   = "templates" + ps + "index.gohtml"
-	recordsPath = "templates" + ps + "records.gohtml"
-	plotPath    = "templates" + ps + "plot.gohtml"
+	basePath    = templatesDir + base + ".gohtml"
+	indexPath   = templatesDir + "index.gohtml"
+	recordsPath = templatesDir + "records.gohtml"
+	plotPath    = templatesDir + "plot.gohtml"
 
-	week  = 24 * 7 * time.Hour
-	month = 24 * 30 * time.Hour
-	year  = 24 * 365 * time.Hour
+	agriculturePath = templatesDir + "agriculture.gohtml"
+	heatmapPath     = templatesDir + "heatmap.gohtml"
 )
 
 var (
-	tmpl    map[string]*template.Template
+	tmpl     map[string]*template.Template
 	tmplOnce sync.Once
-	funcMap = template.FuncMap{
-		"capitalize":       capitalize,
-		"getHex":           getHex,
-		"formatTimestamp":  formatTimestamp,
-		"formatPercent":    formatPercent,
-		"getFavicon":       getFavicon,
-		"getTitle":         getTitle,
-		"getWindDirection": getWindDirection,
+	funcMap  = template.FuncMap{
+		"capitalize":    capitalize,
+		"getHex":        getHex,
+		"formatPercent": formatPercent,
+		"getFavicon":    getFavicon,
+		"iconURL":       iconURL,
+		"getTitle":      getTitle,
+		"add":           func(a, b int) int { return a + b },
+		"plotImage":     newPlotImage,
+		"withSize":      withSize,
+		"languages":     languages,
+		"themes":        func() []string { return themes },
 	}
 
 	palettes = map[string]*bh.Palette{
@@ -55,17 +62,22 @@ var (
 type PageData struct {
 	Zone        string
 	Palette     *bh.Palette
+	Light       *bh.Palette // con il tema automatico, la palette usata se il browser preferisce i colori chiari
 	FontFamily  string
-	OneWeekAgo  int64
-	OneMonthAgo int64
-	OneYearAgo  int64
 	Theme       string
-	From        string
+	Units       UnitSystem
+	Locale      *Locale
+	From        string // from e to da passare ai grafici
 	To          string
+	RangeFrom   string // estremi dell'intervallo per i campi del modulo
+	RangeTo     string
+	RangeError  string
 	Measure     string
+	Year        int
 	Measures    []string
 	Records     []Record
 	Latest      Record
+	Agriculture *Agriculture
 }
 
 func respond(w http.ResponseWriter, data interface{}) {
@@ -74,27 +86,48 @@ func respond(w http.ResponseWriter, data interface{}) {
 	json.NewEncoder(w).Encode(data)
 }
 
-func writePlot(w http.ResponseWriter, b []byte) {
-	w.Header().Set("Content-Type", "image/svg+xml")
+func writePlot(w http.ResponseWriter, b []byte, pf plotFormat) {
+	w.Header().Set("Content-Type", pf.ContentType)
+	w.Header().Add("Vary", "Accept")
 	w.WriteHeader(http.StatusOK)
 	w.Write(b)
 }
 
-func getLimits(r *http.Request) (from int64, to int64, palette *bh.Palette) {
-	q := r.URL.Query()
-	n := time.Now()
+func getLimits(r *http.Request) (from int64, to int64, palette *bh.Palette, err error) {
+	return getQueryLimits(r.URL.Query())
+}
 
-	from, err := strconv.ParseInt(q.Get("from"), 10, 64)
-	if err != nil {
-		from = n.Add(-24 * time.Hour).Unix()
-	}
-	to, err = strconv.ParseInt(q.Get("to"), 10, 64)
+// getQueryLimits è come getLimits, per gli endpoint che usano alcuni parametri in modo diverso.
+func getQueryLimits(q url.Values) (from int64, to int64, palette *bh.Palette, err error) {
+	f, t, err := parseRange(q, time.Now().In(appLocation))
 	if err != nil {
-		to = n.Unix()
+		return
 	}
 
 	palette = getPalette(q)
-	return
+	return f.Unix(), t.Unix(), palette, nil
+}
+
+// getOverlayYears restituisce il numero di anni precedenti da sovrapporre con mode=yoy, oppure 0.
+func getOverlayYears(q url.Values) (int, error) {
+	switch q.Get("mode") {
+	case "":
+		return 0, nil
+	case "yoy":
+	default:
+		return 0, errors.New("modalità non supportata: " + q.Get("mode"))
+	}
+
+	years := q.Get("years")
+	if years == "" {
+		return 1, nil
+	}
+
+	y, err := strconv.Atoi(years)
+	if err != nil || y < 1 || y > maxOverlayYears {
+		return 0, fmt.Errorf("numero di anni non valido, deve essere compreso tra 1 e %d", maxOverlayYears)
+	}
+	return y, nil
 }
 
 func getPalette(q url.Values) *bh.Palette {
@@ -105,30 +138,69 @@ func getPalette(q url.Values) *bh.Palette {
 	return palettes[""]
 }
 
-func getPageData(q url.Values, p *bh.Palette) (*PageData, error) {
+// getTheme restituisce il tema richiesto da riportare nei link e negli URL dei grafici,
+// oppure quello predefinito se il tema non esiste.
+func getTheme(q url.Values) string {
+	if theme := q.Get("theme"); slices.Contains(themes, theme) {
+		return theme
+	}
+	return ""
+}
+
+func getPageData(q url.Values, p *bh.Palette, us UnitSystem, l *Locale) (*PageData, error) {
 	latest, err := getLatestRecord()
 	if err != nil {
 		return nil, err
 	}
 
-	now := time.Now()
+	now := time.Now().In(appLocation)
 
 	funcMu.RLock()
 	z := zone
 	funcMu.RUnlock()
 
-	return &PageData{
-		Zone:        z,
-		Palette:     p,
-		FontFamily:  fontFamily,
-		OneWeekAgo:  now.Add(-week).Unix(),
-		OneMonthAgo: now.Add(-month).Unix(),
-		OneYearAgo:  now.Add(-year).Unix(),
-		Theme:       q.Get("theme"),
-		From:        q.Get("from"),
-		To:          q.Get("to"),
-		Latest:      latest,
-	}, nil
+	var light *bh.Palette
+	if q.Get("theme") == themeAuto {
+		light = palettes["light"]
+	}
+
+	pd := &PageData{
+		Zone:       z,
+		Palette:    p,
+		Light:      light,
+		FontFamily: fontFamily,
+		Theme:      getTheme(q),
+		Units:      us,
+		Locale:     l,
+		Latest:     l.localize([]Record{latest})[0],
+	}
+
+	// Un intervallo non valido viene segnalato nella pagina, che mostra comunque le ultime 24 ore
+	from, to, err := parseRange(q, now)
+	if err != nil {
+		pd.RangeError = err.Error()
+		from, to, _ = parseRange(url.Values{}, now)
+	} else {
+		pd.From, pd.To = rangeParams(q, from, to)
+	}
+	pd.RangeFrom, pd.RangeTo = from.Format(inputLayout), to.Format(inputLayout)
+	return pd, nil
+}
+
+// getPreferences legge il sistema di unità e la lingua di una pagina, salvando nei cookie quelli scelti esplicitamente.
+func getPreferences(w http.ResponseWriter, r *http.Request) (us UnitSystem, l *Locale, err error) {
+	us, err = getUnits(r)
+	if err != nil {
+		return
+	}
+	l, err = getLocale(r)
+	if err != nil {
+		return
+	}
+
+	setUnitsCookie(w, r, us)
+	setLangCookie(w, r, l)
+	return
 }
 
 func executeTemplateSafe(w http.ResponseWriter, t string, data any) {
@@ -141,21 +213,40 @@ func executeTemplateSafe(w http.ResponseWriter, t string, data any) {
 }
 
 func getAPIRecords(w http.ResponseWriter, r *http.Request) {
-	key := r.URL.String()
+	us, err := getUnits(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	l, err := getLocale(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	key := us.String() + "|" + l.Lang + "|" + r.URL.String()
 	if val, ok := apiResponseCache.Get(key); ok {
 		w.Header().Set("Content-Type", "application/json")
 		w.Write(val)
 		return
 	}
 
-	from, to, _ := getLimits(r)
+	from, to, _, err := getLimits(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	records, err := getAllRecords(from, to)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	b, err := json.Marshal(records)
+	b, err := json.Marshal(markGaps(l.localize(records)))
+	if err == nil {
+		b, err = us.convertJSON(b)
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -167,14 +258,20 @@ func getAPIRecords(w http.ResponseWriter, r *http.Request) {
 }
 
 func getAPIConditions(w http.ResponseWriter, r *http.Request) {
-	key := r.URL.String()
+	l, err := getLocale(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	key := l.Lang + "|" + r.URL.String()
 	if val, ok := apiResponseCache.Get(key); ok {
 		w.Header().Set("Content-Type", "application/json")
 		w.Write(val)
 		return
 	}
 
-	b, err := json.Marshal(conditions)
+	b, err := json.Marshal(l.conditions)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -186,7 +283,19 @@ func getAPIConditions(w http.ResponseWriter, r *http.Request) {
 }
 
 func getAPILatest(w http.ResponseWriter, r *http.Request) {
-	key := r.URL.String()
+	us, err := getUnits(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	l, err := getLocale(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	key := us.String() + "|" + l.Lang + "|" + r.URL.String()
 	if val, ok := apiResponseCache.Get(key); ok {
 		w.Header().Set("Content-Type", "application/json")
 		w.Write(val)
@@ -199,7 +308,10 @@ func getAPILatest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	b, err := json.Marshal(latest)
+	b, err := json.Marshal(l.localize([]Record{latest})[0])
+	if err == nil {
+		b, err = us.convertJSON(b)
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -211,7 +323,19 @@ func getAPILatest(w http.ResponseWriter, r *http.Request) {
 }
 
 func getAPIMeta(w http.ResponseWriter, r *http.Request) {
-	key := r.URL.String()
+	us, err := getUnits(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	l, err := getLocale(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	key := us.String() + "|" + l.Lang + "|" + r.URL.String()
 	if val, ok := apiResponseCache.Get(key); ok {
 		w.Header().Set("Content-Type", "application/json")
 		w.Write(val)
@@ -227,10 +351,23 @@ func getAPIMeta(w http.ResponseWriter, r *http.Request) {
 	copy(m, measures)
 	dbMu.RUnlock()
 
+	info := make(map[string]MeasureInfo, len(m))
+	for _, measure := range m {
+		info[measure] = l.Info(us, measure)
+	}
+
+	var langs []string
+	for _, lc := range languages() {
+		langs = append(langs, lc.Lang)
+	}
+
 	data := map[string]any{
-		"zone":     z,
-		"measures": m,
-		"themes":   themes,
+		"zone":         z,
+		"measures":     m,
+		"measure_info": info,
+		"themes":       themes,
+		"units":        us,
+		"languages":    langs,
 	}
 
 	b, err := json.Marshal(data)
@@ -245,37 +382,75 @@ func getAPIMeta(w http.ResponseWriter, r *http.Request) {
 }
 
 func getAPIPlot(w http.ResponseWriter, r *http.Request) {
-	key := r.URL.String()
+	pf, err := getPlotFormat(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	us, err := getUnits(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	l, err := getLocale(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	key := pf.key() + "|" + us.String() + "|" + l.Lang + "|" + r.URL.String()
 	if val, ok := apiResponseCache.Get(key); ok {
-		w.Header().Set("Content-Type", "image/svg+xml")
-		w.Write(val)
+		writePlot(w, val, pf)
 		return
 	}
 
-	from, to, palette := getLimits(r)
+	from, to, palette, err := getLimits(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	measure := r.PathValue("measure")
 	if measure == "" {
 		http.Error(w, "Misura non specificata", http.StatusBadRequest)
 		return
 	}
 
+	years, err := getOverlayYears(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	opts := getPlotOptions(r.URL.Query())
 	f, t := alignConstraints(from, to)
-	cacheKey := getKey([]string{measure, palette.Name}, f, t)
+	cacheKey := pf.key() + "|" + getKey([]string{measure, palette.Name, strconv.Itoa(years), opts.key(), us.String(), l.Lang}, f, t)
 
 	value, ok := plotCache.Get(cacheKey)
 	if ok {
 		apiResponseCache.Add(key, value)
-		writePlot(w, value)
+		writePlot(w, value, pf)
 		return
 	}
 
-	p, err := plotMeasure(measure, f, t, palette)
+	var p *plot.Plot
+	var dec *decorations
+	if years > 0 {
+		p, err = plotOverlay(measure, f, t, years, us, l, palette)
+	} else {
+		dec, err = newDecorations(f, t, opts, l, palette)
+		if err == nil {
+			p, err = plotMeasure(measure, f, t, opts.points(pf), us, l, dec, palette)
+		}
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	dec.foreground(p)
 
-	b, err := getPlotSVG(p, plotWidth, plotHeight)
+	b, err := getPlotImage(p, plotWidth, plotHeight, pf)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -283,35 +458,70 @@ func getAPIPlot(w http.ResponseWriter, r *http.Request) {
 
 	plotCache.Add(cacheKey, b)
 	apiResponseCache.Add(key, b)
-	writePlot(w, b)
+	writePlot(w, b, pf)
 }
 
-func getAPITemp(w http.ResponseWriter, r *http.Request) {
-	key := r.URL.String()
+func getAPICustomPlot(w http.ResponseWriter, r *http.Request) {
+	pf, err := getPlotFormat(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	us, err := getUnits(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	l, err := getLocale(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	key := pf.key() + "|" + us.String() + "|" + l.Lang + "|" + r.URL.String()
 	if val, ok := apiResponseCache.Get(key); ok {
-		w.Header().Set("Content-Type", "image/svg+xml")
-		w.Write(val)
+		writePlot(w, val, pf)
 		return
 	}
 
-	from, to, palette := getLimits(r)
+	from, to, palette, err := getLimits(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	q := r.URL.Query()
+	specs, err := parseSeries(q["m"], palette)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
+	opts := getPlotOptions(q)
 	f, t := alignConstraints(from, to)
-	cacheKey := getKey([]string{"t", palette.Name}, f, t)
+	cacheKey := pf.key() + "|" + getKey(append([]string{"custom", palette.Name, opts.key(), us.String(), l.Lang}, q["m"]...), f, t)
 	value, ok := plotCache.Get(cacheKey)
 	if ok {
 		apiResponseCache.Add(key, value)
-		writePlot(w, value)
+		writePlot(w, value, pf)
 		return
 	}
 
-	p, err := plotTemperature(f, t, palette)
+	dec, err := newDecorations(f, t, opts, l, palette)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	b, err := getPlotSVG(p, plotWidth, plotHeight)
+	p, err := plotCustom(specs, f, t, us, l, dec, palette)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	dec.foreground(p)
+
+	b, err := getPlotImage(p, plotWidth, plotHeight, pf)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -319,35 +529,86 @@ func getAPITemp(w http.ResponseWriter, r *http.Request) {
 
 	plotCache.Add(cacheKey, b)
 	apiResponseCache.Add(key, b)
-	writePlot(w, b)
+	writePlot(w, b, pf)
 }
 
-func getAPIPressure(w http.ResponseWriter, r *http.Request) {
-	key := r.URL.String()
+func getAPITemp(w http.ResponseWriter, r *http.Request) {
+	pf, err := getPlotFormat(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	us, err := getUnits(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	l, err := getLocale(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	key := pf.key() + "|" + us.String() + "|" + l.Lang + "|" + r.URL.String()
 	if val, ok := apiResponseCache.Get(key); ok {
-		w.Header().Set("Content-Type", "image/svg+xml")
-		w.Write(val)
+		writePlot(w, val, pf)
 		return
 	}
 
-	from, to, palette := getLimits(r)
+	from, to, palette, err := getLimits(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	years, err := getOverlayYears(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	f, t := alignConstraints(from, to)
-	cacheKey := getKey([]string{"p", palette.Name}, f, t)
+	box, err := useBoxPlot(r.URL.Query().Get("style"), f, t)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	opts := getPlotOptions(r.URL.Query())
+	cacheKey := pf.key() + "|" + getKey([]string{"t", palette.Name, strconv.Itoa(years), strconv.FormatBool(box), opts.key(), us.String(), l.Lang}, f, t)
 	value, ok := plotCache.Get(cacheKey)
 	if ok {
 		apiResponseCache.Add(key, value)
-		writePlot(w, value)
+		writePlot(w, value, pf)
 		return
 	}
 
-	p, err := plotPressure(f, t, palette)
+	var dec *decorations
+	if years == 0 {
+		dec, err = newDecorations(f, t, opts, l, palette)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	var p *plot.Plot
+	switch {
+	case years > 0:
+		p, err = plotOverlay("temp", f, t, years, us, l, palette)
+	case box:
+		p, err = plotTemperatureBoxes(f, t, us, l, dec, palette)
+	default:
+		p, err = plotTemperature(f, t, opts.points(pf), us, l, dec, palette)
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	dec.foreground(p)
 
-	b, err := getPlotSVG(p, plotWidth, plotHeight)
+	b, err := getPlotImage(p, plotWidth, plotHeight, pf)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -355,81 +616,979 @@ func getAPIPressure(w http.ResponseWriter, r *http.Request) {
 
 	plotCache.Add(cacheKey, b)
 	apiResponseCache.Add(key, b)
-	writePlot(w, b)
+	writePlot(w, b, pf)
 }
 
-func getIndex(w http.ResponseWriter, r *http.Request) {
-	q := r.URL.Query()
-	palette := getPalette(q)
-	pd, err := getPageData(q, palette)
+func getAPIPressure(w http.ResponseWriter, r *http.Request) {
+	pf, err := getPlotFormat(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	executeTemplateSafe(w, indexPath, pd)
-}
+	us, err := getUnits(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-func getRecords(w http.ResponseWriter, r *http.Request) {
-	from, to, palette := getLimits(r)
-	records, err := getAllRecords(from, to)
+	l, err := getLocale(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	key := pf.key() + "|" + us.String() + "|" + l.Lang + "|" + r.URL.String()
+	if val, ok := apiResponseCache.Get(key); ok {
+		writePlot(w, val, pf)
+		return
+	}
+
+	from, to, palette, err := getLimits(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	f, t := alignConstraints(from, to)
+	opts := getPlotOptions(r.URL.Query())
+	cacheKey := pf.key() + "|" + getKey([]string{"p", palette.Name, opts.key(), us.String(), l.Lang}, f, t)
+	value, ok := plotCache.Get(cacheKey)
+	if ok {
+		apiResponseCache.Add(key, value)
+		writePlot(w, value, pf)
+		return
+	}
+
+	dec, err := newDecorations(f, t, opts, l, palette)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	pd, err := getPageData(r.URL.Query(), palette)
+	p, err := plotPressure(f, t, opts.points(pf), us, l, dec, palette)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
-	pd.Records = records
+	dec.foreground(p)
 
-	executeTemplateSafe(w, recordsPath, pd)
+	b, err := getPlotImage(p, plotWidth, plotHeight, pf)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	plotCache.Add(cacheKey, b)
+	apiResponseCache.Add(key, b)
+	writePlot(w, b, pf)
 }
 
-func getPlot(w http.ResponseWriter, r *http.Request) {
+func getDegreeDaysParams(r *http.Request, us UnitSystem) (base float64, method string, err error) {
 	q := r.URL.Query()
-	palette := getPalette(q)
-	pd, err := getPageData(q, palette)
+	base, err = parseBase(q.Get("base"), us)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
 
-	dbMu.RLock()
-	m := make([]string, len(measures))
-	copy(m, measures)
-	dbMu.RUnlock()
+	method = q.Get("method")
+	switch method {
+	case "":
+		method = methodMean
+	case methodMean, methodIntegration:
+	default:
+		err = errors.New("metodo non supportato: " + method)
+	}
+	return
+}
 
-	pd.Measures = m
-	pd.Measure = r.PathValue("measure")
+func getAPIDegreeDays(w http.ResponseWriter, r *http.Request) {
+	us, err := getUnits(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	executeTemplateSafe(w, plotPath, pd)
-}
+	key := us.String() + "|" + r.URL.String()
+	if val, ok := apiResponseCache.Get(key); ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(val)
+		return
+	}
 
-func parseTemplate(path string) *template.Template {
-	return template.Must(template.New(path).Funcs(funcMap).ParseFiles(path, basePath))
-}
+	base, method, err := getDegreeDaysParams(r, us)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-func getServeMux() *http.ServeMux {
-	// init templates
-	tmplOnce.Do(func() {
-		tmpl = make(map[string]*template.Template)
+	from, to, _, err := getLimits(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f, t := alignConstraints(from, to)
+	dd, err := getDegreeDays(f, t, base, method, us)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
-		tmpl[indexPath] = parseTemplate(indexPath)
-		tmpl[recordsPath] = parseTemplate(recordsPath)
-		tmpl[plotPath] = parseTemplate(plotPath)
-	})
+	b, err := json.Marshal(dd)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
-	themesOnce.Do(func() {
-		for k := range palettes {
-			themes = append(themes, k)
-		}
-	})
+	apiResponseCache.Add(key, b)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(b)
+}
 
-	// init conditions
-	var err error
-	conditions, err = loadConditions()
+func getAPIDegreeDaysPlot(w http.ResponseWriter, r *http.Request) {
+	pf, err := getPlotFormat(r)
 	if err != nil {
-		log.Fatalln("Errore nel caricamento delle condizioni:", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	us, err := getUnits(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	l, err := getLocale(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	key := pf.key() + "|" + us.String() + "|" + l.Lang + "|" + r.URL.String()
+	if val, ok := apiResponseCache.Get(key); ok {
+		writePlot(w, val, pf)
+		return
+	}
+
+	base, method, err := getDegreeDaysParams(r, us)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	from, to, palette, err := getLimits(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f, t := alignConstraints(from, to)
+	opts := getPlotOptions(r.URL.Query())
+	cacheKey := pf.key() + "|" + getKey([]string{"dd", strconv.FormatFloat(base, 'f', -1, 64), method, us.String(), l.Lang, palette.Name, opts.key()}, f, t)
+	value, ok := plotCache.Get(cacheKey)
+	if ok {
+		apiResponseCache.Add(key, value)
+		writePlot(w, value, pf)
+		return
+	}
+
+	dec, err := newDecorations(f, t, opts, l, palette)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	p, err := plotDegreeDays(f, t, base, method, us, l, dec, palette)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	dec.foreground(p)
+
+	b, err := getPlotImage(p, plotWidth, plotHeight, pf)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	plotCache.Add(cacheKey, b)
+	apiResponseCache.Add(key, b)
+	writePlot(w, b, pf)
+}
+
+func getAgricultureParams(r *http.Request) (s Season, year int, err error) {
+	s, err = getSeason()
+	if err != nil {
+		return
+	}
+
+	year = s.currentYear(time.Now().In(appLocation))
+	if y := r.URL.Query().Get("year"); y != "" {
+		year, err = strconv.Atoi(y)
+		if err != nil {
+			err = errors.New("anno non valido: " + y)
+		}
+	}
+	return
+}
+
+func getAPIAgriculture(w http.ResponseWriter, r *http.Request) {
+	us, err := getUnits(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	key := us.String() + "|" + r.URL.String()
+	if val, ok := apiResponseCache.Get(key); ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(val)
+		return
+	}
+
+	s, year, err := getAgricultureParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	a, err := getAgriculture(s, year, us)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	b, err := json.Marshal(a)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	apiResponseCache.Add(key, b)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(b)
+}
+
+func getAPIAgriculturePlot(w http.ResponseWriter, r *http.Request) {
+	pf, err := getPlotFormat(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	us, err := getUnits(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	l, err := getLocale(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	key := pf.key() + "|" + us.String() + "|" + l.Lang + "|" + r.URL.String()
+	if val, ok := apiResponseCache.Get(key); ok {
+		writePlot(w, val, pf)
+		return
+	}
+
+	s, year, err := getAgricultureParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	previous := 2
+	if v := r.URL.Query().Get("previous"); v != "" {
+		previous, err = strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "Numero di stagioni non valido", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Le decorazioni riguardano la stagione richiesta, su cui sono allineate le precedenti
+	palette := getPalette(r.URL.Query())
+	from, to := s.bounds(year)
+	f, t := alignConstraints(from.Unix(), to.Unix()-1)
+	dec, err := newDecorations(f, t, getPlotOptions(r.URL.Query()), l, palette)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	p, err := plotAgriculture(s, year, previous, us, l, dec, palette)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	dec.foreground(p)
+
+	b, err := getPlotImage(p, plotWidth, plotHeight, pf)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	apiResponseCache.Add(key, b)
+	writePlot(w, b, pf)
+}
+
+func getPrecipitationParams(r *http.Request) (period string, err error) {
+	period = r.URL.Query().Get("period")
+	switch period {
+	case "":
+		period = periodDay
+	case periodDay, periodWeek, periodMonth:
+	default:
+		err = errors.New("periodo non supportato: " + period)
+	}
+	return
+}
+
+func getAPIPrecipitation(w http.ResponseWriter, r *http.Request) {
+	us, err := getUnits(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	key := us.String() + "|" + r.URL.String()
+	if val, ok := apiResponseCache.Get(key); ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(val)
+		return
+	}
+
+	period, err := getPrecipitationParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Qui period indica il raggruppamento dei totali, non un intervallo con nome
+	q := r.URL.Query()
+	q.Del("period")
+	from, to, _, err := getQueryLimits(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f, t := alignConstraints(from, to)
+	p, err := getPrecipitation(f, t, period)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	p.convert(us)
+
+	b, err := json.Marshal(p)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	apiResponseCache.Add(key, b)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(b)
+}
+
+func getAPIGaps(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.String()
+	if val, ok := apiResponseCache.Get(key); ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(val)
+		return
+	}
+
+	from, to, _, err := getLimits(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f, t := alignConstraints(from, to)
+	gaps, err := getGaps(f, t)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	b, err := json.Marshal(gaps)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	apiResponseCache.Add(key, b)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(b)
+}
+
+func getAPIAnomaly(w http.ResponseWriter, r *http.Request) {
+	us, err := getUnits(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	key := us.String() + "|" + r.URL.String()
+	if val, ok := apiResponseCache.Get(key); ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(val)
+		return
+	}
+
+	from, to, _, err := getLimits(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	measure := r.PathValue("measure")
+	if measure == "" {
+		http.Error(w, "Misura non specificata", http.StatusBadRequest)
+		return
+	}
+
+	f, t := alignConstraints(from, to)
+	a, err := getAnomaly(measure, f, t, us)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	b, err := json.Marshal(a)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	apiResponseCache.Add(key, b)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(b)
+}
+
+func getAPIAnomalyPlot(w http.ResponseWriter, r *http.Request) {
+	pf, err := getPlotFormat(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	us, err := getUnits(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	l, err := getLocale(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	key := pf.key() + "|" + us.String() + "|" + l.Lang + "|" + r.URL.String()
+	if val, ok := apiResponseCache.Get(key); ok {
+		writePlot(w, val, pf)
+		return
+	}
+
+	from, to, palette, err := getLimits(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	measure := r.PathValue("measure")
+	if measure == "" {
+		http.Error(w, "Misura non specificata", http.StatusBadRequest)
+		return
+	}
+
+	f, t := alignConstraints(from, to)
+	opts := getPlotOptions(r.URL.Query())
+	cacheKey := pf.key() + "|" + getKey([]string{"anomaly", measure, palette.Name, opts.key(), us.String(), l.Lang}, f, t)
+
+	value, ok := plotCache.Get(cacheKey)
+	if ok {
+		apiResponseCache.Add(key, value)
+		writePlot(w, value, pf)
+		return
+	}
+
+	dec, err := newDecorations(f, t, opts, l, palette)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	p, err := plotAnomaly(measure, f, t, us, l, dec, palette)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	dec.foreground(p)
+
+	b, err := getPlotImage(p, plotWidth, plotHeight, pf)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	plotCache.Add(cacheKey, b)
+	apiResponseCache.Add(key, b)
+	writePlot(w, b, pf)
+}
+
+func getAPIWindRose(w http.ResponseWriter, r *http.Request) {
+	pf, err := getPlotFormat(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	us, err := getUnits(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	l, err := getLocale(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	key := pf.key() + "|" + us.String() + "|" + l.Lang + "|" + r.URL.String()
+	if val, ok := apiResponseCache.Get(key); ok {
+		writePlot(w, val, pf)
+		return
+	}
+
+	from, to, palette, err := getLimits(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	f, t := alignConstraints(from, to)
+	cacheKey := pf.key() + "|" + getKey([]string{"windrose", palette.Name, us.String(), l.Lang}, f, t)
+	value, ok := plotCache.Get(cacheKey)
+	if ok {
+		apiResponseCache.Add(key, value)
+		writePlot(w, value, pf)
+		return
+	}
+
+	p, err := plotWindRose(f, t, us, l, palette)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	b, err := getPlotImage(p, plotWidth, plotHeight, pf)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	plotCache.Add(cacheKey, b)
+	apiResponseCache.Add(key, b)
+	writePlot(w, b, pf)
+}
+
+func getYear(q url.Values) (int, error) {
+	y := q.Get("year")
+	if y == "" {
+		return time.Now().In(appLocation).Year(), nil
+	}
+
+	year, err := strconv.Atoi(y)
+	if err != nil {
+		return 0, errors.New("anno non valido: " + y)
+	}
+	return year, nil
+}
+
+func getAPIHeatmap(w http.ResponseWriter, r *http.Request) {
+	pf, err := getPlotFormat(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	us, err := getUnits(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	l, err := getLocale(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	key := pf.key() + "|" + us.String() + "|" + l.Lang + "|" + r.URL.String()
+	if val, ok := apiResponseCache.Get(key); ok {
+		writePlot(w, val, pf)
+		return
+	}
+
+	q := r.URL.Query()
+	palette := getPalette(q)
+	measure := r.PathValue("measure")
+	year, err := getYear(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	cacheKey := pf.key() + "|" + fmt.Sprintf("heatmap|%s|%s|%d|%s|%s", measure, palette.Name, year, us, l.Lang)
+	value, ok := plotCache.Get(cacheKey)
+	if ok {
+		apiResponseCache.Add(key, value)
+		writePlot(w, value, pf)
+		return
+	}
+
+	p, err := plotHeatmap(measure, year, us, l, palette)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	b, err := getPlotImage(p, plotWidth, heatmapHeight, pf)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	plotCache.Add(cacheKey, b)
+	apiResponseCache.Add(key, b)
+	writePlot(w, b, pf)
+}
+
+func getAPIScatter(w http.ResponseWriter, r *http.Request) {
+	pf, err := getPlotFormat(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	us, err := getUnits(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	l, err := getLocale(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	key := pf.key() + "|" + us.String() + "|" + l.Lang + "|" + r.URL.String()
+	if val, ok := apiResponseCache.Get(key); ok {
+		writePlot(w, val, pf)
+		return
+	}
+
+	q := r.URL.Query()
+	x, y, colorBy := q.Get("x"), q.Get("y"), q.Get("color")
+	if x == "" || y == "" {
+		http.Error(w, "Misure non specificate", http.StatusBadRequest)
+		return
+	}
+
+	from, to, palette, err := getLimits(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f, t := alignConstraints(from, to)
+	cacheKey := pf.key() + "|" + getKey([]string{"scatter", x, y, colorBy, palette.Name, us.String(), l.Lang}, f, t)
+	value, ok := plotCache.Get(cacheKey)
+	if ok {
+		apiResponseCache.Add(key, value)
+		writePlot(w, value, pf)
+		return
+	}
+
+	p, err := plotScatter(x, y, colorBy, f, t, us, l, palette)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	b, err := getPlotImage(p, plotWidth, plotHeight, pf)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	plotCache.Add(cacheKey, b)
+	apiResponseCache.Add(key, b)
+	writePlot(w, b, pf)
+}
+
+func getAPIHistogram(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	asJSON := q.Get("format") == "json"
+	contentType := "application/json"
+	var pf plotFormat
+	if !asJSON {
+		var err error
+		pf, err = getPlotFormat(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		contentType = pf.ContentType
+	}
+
+	us, err := getUnits(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	l, err := getLocale(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	key := pf.key() + "|" + us.String() + "|" + l.Lang + "|" + r.URL.String()
+	if val, ok := apiResponseCache.Get(key); ok {
+		w.Header().Set("Content-Type", contentType)
+		w.Write(val)
+		return
+	}
+
+	from, to, palette, err := getLimits(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	measure := r.PathValue("measure")
+
+	bins := defaultBins
+	if v := q.Get("bins"); v != "" {
+		bins, err = strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "Numero di intervalli non valido", http.StatusBadRequest)
+			return
+		}
+	}
+
+	f, t := alignConstraints(from, to)
+	if asJSON {
+		h, err := getHistogram(measure, bins, f, t, us)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		b, err := json.Marshal(h)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		apiResponseCache.Add(key, b)
+		w.Header().Set("Content-Type", contentType)
+		w.Write(b)
+		return
+	}
+
+	cacheKey := pf.key() + "|" + getKey([]string{"histogram", measure, strconv.Itoa(bins), palette.Name, us.String(), l.Lang}, f, t)
+	value, ok := plotCache.Get(cacheKey)
+	if ok {
+		apiResponseCache.Add(key, value)
+		writePlot(w, value, pf)
+		return
+	}
+
+	p, err := plotHistogram(measure, bins, f, t, us, l, palette)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	b, err := getPlotImage(p, plotWidth, plotHeight, pf)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	plotCache.Add(cacheKey, b)
+	apiResponseCache.Add(key, b)
+	writePlot(w, b, pf)
+}
+
+func getIndex(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	palette := getPalette(q)
+	us, l, err := getPreferences(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	pd, err := getPageData(q, palette, us, l)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+
+	executeTemplateSafe(w, indexPath, pd)
+}
+
+func getRecords(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	palette := getPalette(q)
+	us, l, err := getPreferences(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	pd, err := getPageData(q, palette, us, l)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if pd.RangeError == "" {
+		from, to, _, err := getLimits(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		records, err := getAllRecords(from, to)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		pd.Records = l.localize(records)
+	}
+
+	executeTemplateSafe(w, recordsPath, pd)
+}
+
+func getPlot(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	palette := getPalette(q)
+	us, l, err := getPreferences(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	pd, err := getPageData(q, palette, us, l)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+
+	dbMu.RLock()
+	m := make([]string, len(measures))
+	copy(m, measures)
+	dbMu.RUnlock()
+
+	pd.Measures = m
+	pd.Measure = r.PathValue("measure")
+
+	executeTemplateSafe(w, plotPath, pd)
+}
+
+func getAgriculturePage(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	palette := getPalette(q)
+	us, l, err := getPreferences(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	pd, err := getPageData(q, palette, us, l)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s, year, err := getAgricultureParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	a, err := getAgriculture(s, year, us)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	pd.Agriculture = &a
+
+	executeTemplateSafe(w, agriculturePath, pd)
+}
+
+func getHeatmapPage(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	palette := getPalette(q)
+	us, l, err := getPreferences(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	pd, err := getPageData(q, palette, us, l)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	pd.Year, err = getYear(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	pd.Measure = r.PathValue("measure")
+
+	executeTemplateSafe(w, heatmapPath, pd)
+}
+
+func parseTemplate(path string) *template.Template {
+	return template.Must(template.New(path).Funcs(funcMap).ParseFS(getAssets(), path, basePath))
+}
+
+func getServeMux() *http.ServeMux {
+	// init templates
+	tmplOnce.Do(func() {
+		tmpl = make(map[string]*template.Template)
+
+		tmpl[indexPath] = parseTemplate(indexPath)
+		tmpl[recordsPath] = parseTemplate(recordsPath)
+		tmpl[plotPath] = parseTemplate(plotPath)
+		tmpl[agriculturePath] = parseTemplate(agriculturePath)
+		tmpl[heatmapPath] = parseTemplate(heatmapPath)
+	})
+
+	themesOnce.Do(func() {
+		if err := loadThemes(); err != nil {
+			log.Fatalln("Errore nel caricamento dei temi:", err)
+		}
+		themes = append(slices.Sorted(maps.Keys(palettes)), themeAuto)
+	})
+
+	// init conditions
+	var err error
+	conditions, err = loadConditions()
+	if err != nil {
+		log.Fatalln("Errore nel caricamento delle condizioni:", err)
+	}
+	_, err = loadLocales()
+	if err != nil {
+		log.Fatalln("Errore nel caricamento delle lingue:", err)
 	}
 
 	// init router
@@ -439,14 +1598,31 @@ func getServeMux() *http.ServeMux {
 	s.HandleFunc("GET /api/conditions", getAPIConditions)
 	s.HandleFunc("GET /api/latest", getAPILatest)
 	s.HandleFunc("GET /api/meta", getAPIMeta)
+	s.HandleFunc("GET /api/plot", getAPICustomPlot)
 	s.HandleFunc("GET /api/plot/{measure}", getAPIPlot)
 	s.HandleFunc("GET /api/temp", getAPITemp)
 	s.HandleFunc("GET /api/pressure", getAPIPressure)
+	s.HandleFunc("GET /api/windrose", getAPIWindRose)
+	s.HandleFunc("GET /api/scatter", getAPIScatter)
+	s.HandleFunc("GET /api/histogram/{measure}", getAPIHistogram)
+	s.HandleFunc("GET /api/degree-days", getAPIDegreeDays)
+	s.HandleFunc("GET /api/degree-days/plot", getAPIDegreeDaysPlot)
+	s.HandleFunc("GET /api/agriculture", getAPIAgriculture)
+	s.HandleFunc("GET /api/agriculture/plot", getAPIAgriculturePlot)
+	s.HandleFunc("GET /api/precipitation", getAPIPrecipitation)
+	s.HandleFunc("GET /api/gaps", getAPIGaps)
+	s.HandleFunc("GET /api/anomaly/{measure}", getAPIAnomaly)
+	s.HandleFunc("GET /api/anomaly/{measure}/plot", getAPIAnomalyPlot)
+	s.HandleFunc("GET /api/heatmap/{measure}", getAPIHeatmap)
+
+	s.HandleFunc("GET /static/icons/{icon}", getIconsHandler())
 
 	s.HandleFunc("GET /", getIndex)
 	s.HandleFunc("GET /records", getRecords)
 	s.HandleFunc("GET /plot/{measure}", getPlot)
 	s.HandleFunc("GET /plot/", getPlot)
+	s.HandleFunc("GET /agriculture", getAgriculturePage)
+	s.HandleFunc("GET /heatmap/{measure}", getHeatmapPage)
 
 	return s
 }
@@ -0,0 +1,59 @@
+package src
+
+import (
+	"embed"
+	"io/fs"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Risorse incluse nel binario: template, condizioni meteo, traduzioni e icone
+//
+//go:embed templates/*.gohtml conditions*.json locales/*.json static/icons/*.svg
+var embeddedAssets embed.FS
+
+// overlayFS cerca ogni file prima nella cartella di personalizzazione, poi tra le risorse incluse.
+type overlayFS struct {
+	dir  fs.FS
+	base fs.FS
+}
+
+func (o overlayFS) Open(name string) (fs.File, error) {
+	f, err := o.dir.Open(name)
+	if err == nil {
+		return f, nil
+	}
+	return o.base.Open(name)
+}
+
+// ReadDir unisce il contenuto delle due cartelle, così da poter aggiungere file (es. nuove lingue).
+func (o overlayFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	base, err := fs.ReadDir(o.base, name)
+	dir, dirErr := fs.ReadDir(o.dir, name)
+	if err != nil && dirErr != nil {
+		return nil, err
+	}
+
+	entries := make(map[string]fs.DirEntry, len(base)+len(dir))
+	for _, e := range base {
+		entries[e.Name()] = e
+	}
+	for _, e := range dir {
+		entries[e.Name()] = e
+	}
+	return slices.SortedFunc(maps.Values(entries), func(a, b fs.DirEntry) int {
+		return strings.Compare(a.Name(), b.Name())
+	}), nil
+}
+
+// getAssets restituisce le risorse dell'applicazione. Se ASSETS_DIR è impostata, i file presenti
+// in quella cartella (con gli stessi percorsi, es. templates/index.gohtml) sostituiscono quelli inclusi.
+func getAssets() fs.FS {
+	dir := os.Getenv("ASSETS_DIR")
+	if dir == "" {
+		return embeddedAssets
+	}
+	return overlayFS{dir: os.DirFS(dir), base: embeddedAssets}
+}
@@ -0,0 +1,64 @@
+package src
+
+import (
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestServerFromAnyDirectory(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := initDB(); err != nil {
+		t.Fatal(err)
+	}
+	cronInterval = 1800
+	if err := db.Create(&Record{Dt: time.Now().Unix(), Temp: 20, Weather: "800"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	s := getServeMux()
+
+	for _, path := range []string{"/", "/records", "/api/conditions?lang=it", "/static/icons/01d.svg"} {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: status %d: %s", path, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestOverlayFS(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "locales"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{"locales/de.json": "de", "locales/it.json": "custom"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	o := overlayFS{dir: os.DirFS(dir), base: fstest.MapFS{
+		"locales/it.json": {Data: []byte("embedded")},
+		"locales/fr.json": {Data: []byte("fr")},
+	}}
+
+	files, err := fs.Glob(o, "locales/*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"locales/de.json", "locales/fr.json", "locales/it.json"}; !slices.Equal(files, want) {
+		t.Errorf("got %v, want %v", files, want)
+	}
+
+	for name, want := range map[string]string{"locales/it.json": "custom", "locales/fr.json": "fr"} {
+		b, err := fs.ReadFile(o, name)
+		if err != nil || string(b) != want {
+			t.Errorf("%s: got %q, %v; want %q", name, b, err, want)
+		}
+	}
+}
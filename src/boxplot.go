@@ -0,0 +1,160 @@
+package src
+
+import (
+	"errors"
+	"image/color"
+	"slices"
+	"time"
+
+	bh "github.com/birabittoh/bunnyhue"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+const (
+	styleLine = "line"
+	styleBox  = "box"
+
+	// Oltre questo intervallo il grafico della temperatura mostra un box per giorno
+	boxPlotThreshold = 2 * week
+	boxWidth         = 0.7 // frazione della giornata
+)
+
+// DailyStats contiene le statistiche di una misura in un giorno.
+type DailyStats struct {
+	Day    time.Time
+	Min    float64
+	Q1     float64
+	Median float64
+	Q3     float64
+	Max    float64
+	Mean   float64
+}
+
+// quantile calcola il quantile q di valori già ordinati, con interpolazione lineare.
+func quantile(sorted []float64, q float64) float64 {
+	pos := q * float64(len(sorted)-1)
+	i := int(pos)
+	if i+1 >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[i] + (sorted[i+1]-sorted[i])*(pos-float64(i))
+}
+
+func computeDailyStats(dp []DataPoint) (stats []DailyStats) {
+	var values []float64
+	var day time.Time
+	flush := func() {
+		if len(values) == 0 {
+			return
+		}
+		slices.Sort(values)
+
+		var sum float64
+		for _, v := range values {
+			sum += v
+		}
+		stats = append(stats, DailyStats{
+			Day:    day,
+			Min:    values[0],
+			Q1:     quantile(values, 0.25),
+			Median: quantile(values, 0.5),
+			Q3:     quantile(values, 0.75),
+			Max:    values[len(values)-1],
+			Mean:   sum / float64(len(values)),
+		})
+		values = values[:0]
+	}
+
+	for _, p := range dp {
+		d := dayStart(localTime(int64(p.Dt)))
+		if !d.Equal(day) {
+			flush()
+			day = d
+		}
+		values = append(values, p.Value0)
+	}
+	flush()
+	return
+}
+
+// dailyBoxes implementa plot.Plotter disegnando per ogni giorno una linea tra minima e massima,
+// un box tra il primo e il terzo quartile, la mediana e un punto per la media.
+type dailyBoxes struct {
+	stats  []DailyStats
+	box    color.Color
+	line   color.Color
+	mean   color.Color
+	radius vg.Length
+}
+
+func (b *dailyBoxes) Plot(c draw.Canvas, plt *plot.Plot) {
+	trX, trY := plt.Transforms(&c)
+	line := draw.LineStyle{Color: b.line, Width: vg.Points(1)}
+	half := boxWidth * 12 * 60 * 60
+
+	for _, s := range b.stats {
+		mid := float64(s.Day.Add(12 * time.Hour).Unix())
+		x, x0, x1 := trX(mid), trX(mid-half), trX(mid+half)
+
+		c.StrokeLines(line, c.ClipLinesXY([]vg.Point{{X: x, Y: trY(s.Min)}, {X: x, Y: trY(s.Q1)}})...)
+		c.StrokeLines(line, c.ClipLinesXY([]vg.Point{{X: x, Y: trY(s.Q3)}, {X: x, Y: trY(s.Max)}})...)
+
+		box := []vg.Point{{X: x0, Y: trY(s.Q1)}, {X: x0, Y: trY(s.Q3)}, {X: x1, Y: trY(s.Q3)}, {X: x1, Y: trY(s.Q1)}}
+		c.FillPolygon(b.box, c.ClipPolygonXY(box))
+		c.StrokeLines(line, c.ClipLinesXY(append(box, box[0]))...)
+		c.StrokeLines(line, c.ClipLinesXY([]vg.Point{{X: x0, Y: trY(s.Median)}, {X: x1, Y: trY(s.Median)}})...)
+
+		if pt := (vg.Point{X: x, Y: trY(s.Mean)}); c.Contains(pt) {
+			draw.CircleGlyph{}.DrawGlyph(&c, draw.GlyphStyle{Color: b.mean, Radius: b.radius, Shape: draw.CircleGlyph{}}, pt)
+		}
+	}
+}
+
+func (b *dailyBoxes) DataRange() (xmin, xmax, ymin, ymax float64) {
+	if len(b.stats) == 0 {
+		return 0, 0, 0, 0
+	}
+	xmin = float64(b.stats[0].Day.Unix())
+	xmax = float64(b.stats[len(b.stats)-1].Day.Add(24 * time.Hour).Unix())
+	ymin, ymax = b.stats[0].Min, b.stats[0].Max
+	for _, s := range b.stats {
+		ymin = min(ymin, s.Min)
+		ymax = max(ymax, s.Max)
+	}
+	return
+}
+
+// useBoxPlot decide se mostrare un box per giorno in base allo stile richiesto e all'intervallo.
+func useBoxPlot(style string, f, t *int64) (bool, error) {
+	switch style {
+	case "":
+		return time.Duration(*t-*f)*time.Second > boxPlotThreshold, nil
+	case styleLine:
+		return false, nil
+	case styleBox:
+		return true, nil
+	}
+	return false, errors.New("stile non supportato: " + style)
+}
+
+func plotTemperatureBoxes(f, t *int64, us UnitSystem, l *Locale, dec *decorations, palette *bh.Palette) (p *plot.Plot, err error) {
+	dp, err := us.dataPoints([]string{"temp"}, f, t)
+	if err != nil {
+		err = errors.New("errore nella lettura dei dati: " + err.Error())
+		return
+	}
+
+	stats := computeDailyStats(dp)
+	p = newPlot(l, palette)
+	dec.background(p)
+	setAxisLabel(&p.Y, l.Label(us, "temp"))
+
+	boxes := &dailyBoxes{stats: stats, box: palette.Blue, line: palette.Primary, mean: palette.Orange, radius: vg.Points(1.5)}
+	p.Add(boxes)
+	p.Legend.Add("Q1-Q3", legendThumb{palette.Blue})
+	p.Legend.Add(l.T("Mean"), legendThumb{palette.Orange})
+
+	return
+}
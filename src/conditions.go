@@ -3,10 +3,8 @@ package src
 import (
 	"encoding/json"
 	"log"
-	"os"
 	"strings"
 	"sync"
-	"time"
 )
 
 var (
@@ -24,7 +22,7 @@ type Condition struct {
 
 func loadConditions() (map[string]Condition, error) {
 	condOnce.Do(func() {
-		file, err := os.Open("conditions.json")
+		file, err := getAssets().Open("conditions.json")
 		if err != nil {
 			condErr = err
 			return
@@ -42,16 +40,20 @@ func loadConditions() (map[string]Condition, error) {
 }
 
 func (record *Record) parseConditions() {
-	dt := time.Unix(record.Dt, 0)
-	sunrise := time.Unix(record.Sunrise, 0)
-	sunset := time.Unix(record.Sunset, 0)
-
-	weatherIDs := strings.Split(record.Weather, ",")
-
 	condMu.RLock()
 	defer condMu.RUnlock()
+	record.Conditions = record.getConditions(conditions)
+}
+
+// getConditions costruisce le condizioni del record usando la tabella di una lingua.
+func (record *Record) getConditions(table map[string]Condition) (cs []Condition) {
+	dt := localTime(record.Dt)
+	sunrise := localTime(record.Sunrise)
+	sunset := localTime(record.Sunset)
+
+	weatherIDs := strings.Split(record.Weather, ",")
 	for _, w := range weatherIDs {
-		c, ok := conditions[w]
+		c, ok := table[w]
 		if !ok {
 			log.Printf("Condizione meteo non trovata per ID %s\n", w)
 			continue
@@ -65,6 +67,7 @@ func (record *Record) parseConditions() {
 
 		c.Description = strings.Split(c.Description, ": ")[0]
 
-		record.Conditions = append(record.Conditions, c)
+		cs = append(cs, c)
 	}
+	return
 }
@@ -0,0 +1,223 @@
+package src
+
+import (
+	"errors"
+	"image/color"
+	"slices"
+	"strings"
+
+	bh "github.com/birabittoh/bunnyhue"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/text"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+// seriesSpec descrive una serie di un grafico personalizzato, nella forma "misura[:colore][:dash]".
+type seriesSpec struct {
+	Measure string
+	Color   color.Color
+	Dashed  bool
+}
+
+func paletteColor(palette *bh.Palette, name string) (color.Color, bool) {
+	colors := map[string]color.Color{
+		"primary":   palette.Primary,
+		"secondary": palette.Secondary,
+		"blue":      palette.Blue,
+		"red":       palette.Red,
+		"green":     palette.Green,
+		"pink":      palette.Pink,
+		"purple":    palette.Purple,
+		"cyan":      palette.Cyan,
+		"orange":    palette.Orange,
+		"teal":      palette.Teal,
+		"brown":     palette.Brown,
+		"lime":      palette.Lime,
+	}
+	c, ok := colors[name]
+	return c, ok
+}
+
+func parseSeries(values []string, palette *bh.Palette) (specs []seriesSpec, err error) {
+	if len(values) == 0 {
+		err = errors.New("nessuna misura specificata")
+		return
+	}
+
+	defaults := seriesColors(palette)
+	for i, v := range values {
+		parts := strings.Split(v, ":")
+		spec := seriesSpec{Measure: parts[0], Color: defaults[i%len(defaults)]}
+		for _, opt := range parts[1:] {
+			if opt == "dash" {
+				spec.Dashed = true
+				continue
+			}
+			c, ok := paletteColor(palette, opt)
+			if !ok {
+				err = errors.New("opzione non valida per " + parts[0] + ": " + opt)
+				return
+			}
+			spec.Color = c
+		}
+		specs = append(specs, spec)
+	}
+	return
+}
+
+// rightAxis implementa plot.Plotter disegnando un secondo asse Y sul lato destro del grafico.
+// I valori delle serie di destra vengono riscalati nell'intervallo dell'asse di sinistra.
+type rightAxis struct {
+	min, max   float64 // intervallo dei valori di destra
+	lmin, lmax float64 // intervallo corrispondente sull'asse di sinistra
+	label      string
+	color      color.Color
+	text       text.Style
+}
+
+const rightAxisPadding = 6 // distanza delle etichette dall'asse, in punti
+
+func (a *rightAxis) scale(v float64) float64 {
+	if a.max == a.min {
+		return (a.lmin + a.lmax) / 2
+	}
+	return a.lmin + (v-a.min)/(a.max-a.min)*(a.lmax-a.lmin)
+}
+
+func (a *rightAxis) ticks() []plot.Tick {
+	return (plot.DefaultTicks{}).Ticks(a.min, a.max)
+}
+
+// GlyphBoxes riserva a destra dell'area dei dati lo spazio per le etichette.
+func (a *rightAxis) GlyphBoxes(plt *plot.Plot) []plot.GlyphBox {
+	width := a.text.Width(a.label)
+	for _, tick := range a.ticks() {
+		if !tick.IsMinor() {
+			width = max(width, a.text.Width(tick.Label))
+		}
+	}
+	h := a.text.Height(a.label)
+	return []plot.GlyphBox{{
+		X:         1,
+		Y:         0.5,
+		Rectangle: vg.Rectangle{Min: vg.Point{Y: -h / 2}, Max: vg.Point{X: width + vg.Points(rightAxisPadding), Y: h / 2}},
+	}}
+}
+
+func (a *rightAxis) Plot(c draw.Canvas, plt *plot.Plot) {
+	_, trY := plt.Transforms(&c)
+	line := draw.LineStyle{Color: a.color, Width: vg.Points(0.5)}
+	x := c.Max.X
+	c.StrokeLine2(line, x, c.Min.Y, x, c.Max.Y)
+
+	sty := a.text
+	sty.Color = a.color
+	sty.XAlign, sty.YAlign = draw.XLeft, draw.YCenter
+	for _, tick := range a.ticks() {
+		y := trY(a.scale(tick.Value))
+		if tick.IsMinor() {
+			c.StrokeLine2(line, x-vg.Points(2), y, x, y)
+			continue
+		}
+		c.StrokeLine2(line, x-vg.Points(4), y, x, y)
+		c.FillText(sty, vg.Point{X: x + vg.Points(rightAxisPadding), Y: y}, tick.Label)
+	}
+
+	sty.YAlign = draw.YTop
+	c.FillText(sty, vg.Point{X: x + vg.Points(rightAxisPadding), Y: c.Max.Y}, a.label)
+}
+
+// plotCustom disegna qualsiasi combinazione di misure; se le unità sono due, le serie
+// della seconda unità vengono assegnate a un asse Y a destra.
+func plotCustom(specs []seriesSpec, f, t *int64, us UnitSystem, l *Locale, dec *decorations, palette *bh.Palette) (p *plot.Plot, err error) {
+	var m, units []string
+	for _, s := range specs {
+		m = append(m, s.Measure)
+		u := us.Unit(s.Measure)
+		if !slices.Contains(units, u) {
+			units = append(units, u)
+		}
+	}
+	if len(units) > 2 {
+		err = errors.New("sono supportate al massimo due unità diverse")
+		return
+	}
+
+	dp, err := us.dataPoints(m, f, t)
+	if err != nil {
+		err = errors.New("errore nella lettura dei dati: " + err.Error())
+		return
+	}
+
+	series := make([]plotter.XYs, len(specs))
+	for i := range series {
+		series[i] = make(plotter.XYs, len(dp))
+	}
+	for j := range dp {
+		for i := range specs {
+			series[i][j].X = dp[j].Dt
+			series[i][j].Y = dp[j].value(i)
+		}
+	}
+
+	p = newPlot(l, palette)
+	dec.background(p)
+	setAxisLabel(&p.Y, units[0])
+
+	// Prima le serie di sinistra, per conoscere l'intervallo dell'asse
+	for i, s := range specs {
+		if us.Unit(s.Measure) != units[0] {
+			continue
+		}
+		err = addTimeLines(p, series[i], 0, s.Color, s.Dashed, l.MeasureName(s.Measure))
+		if err != nil {
+			return
+		}
+	}
+	if len(units) == 1 {
+		return
+	}
+
+	// Con le serie di sinistra costanti l'intervallo va allargato, o le tacche di destra coinciderebbero
+	if p.Y.Min == p.Y.Max {
+		p.Y.Min--
+		p.Y.Max++
+	}
+	axis := &rightAxis{label: units[1], color: palette.Primary, text: p.Legend.TextStyle, lmin: p.Y.Min, lmax: p.Y.Max}
+	first := true
+	for i, s := range specs {
+		if us.Unit(s.Measure) != units[1] {
+			continue
+		}
+		for _, pt := range series[i] {
+			if first {
+				axis.min, axis.max = pt.Y, pt.Y
+				first = false
+			}
+			axis.min = min(axis.min, pt.Y)
+			axis.max = max(axis.max, pt.Y)
+		}
+	}
+	if len(dp) == 0 {
+		return
+	}
+
+	for i, s := range specs {
+		if us.Unit(s.Measure) != units[1] {
+			continue
+		}
+		scaled := make(plotter.XYs, len(series[i]))
+		for j, pt := range series[i] {
+			scaled[j] = plotter.XY{X: pt.X, Y: axis.scale(pt.Y)}
+		}
+		err = addTimeLines(p, scaled, 0, s.Color, s.Dashed, l.MeasureName(s.Measure)+" ("+units[1]+")")
+		if err != nil {
+			return
+		}
+	}
+	p.Add(axis)
+
+	return
+}
@@ -68,6 +68,7 @@ type Record struct {
 	Weather string `json:"weather"`
 
 	Conditions []Condition `json:"conditions" gorm:"-"`
+	GapBefore  bool        `json:"gap_before,omitempty" gorm:"-"` // preceduto da un buco nei dati
 }
 
 func alignConstraints(from int64, to int64) (f, t *int64) {
@@ -154,6 +155,15 @@ func getDataPoints(requestedMeasures []string, f, t *int64) (dp []DataPoint, err
 		return
 	}
 
+	if slices.ContainsFunc(requestedMeasures, isDerived) {
+		dp, err = getDerivedDataPoints(requestedMeasures, f, t)
+		if err != nil {
+			return
+		}
+		dpCache.Add(key, dp)
+		return
+	}
+
 	selectText := "dt"
 	for i, measure := range requestedMeasures {
 		selectText += ", " + measure + " as value" + strconv.Itoa(i)
@@ -172,6 +182,39 @@ func getDataPoints(requestedMeasures []string, f, t *int64) (dp []DataPoint, err
 	return
 }
 
+// getDerivedDataPoints legge le colonne necessarie e calcola in Go le misure derivate.
+func getDerivedDataPoints(requestedMeasures []string, f, t *int64) (dp []DataPoint, err error) {
+	columns := []string{"dt"}
+	for _, measure := range requestedMeasures {
+		needed := []string{measure}
+		if d, ok := getDerivedMeasure(measure); ok {
+			needed = d.Columns
+		}
+		for _, c := range needed {
+			if !slices.Contains(columns, c) {
+				columns = append(columns, c)
+			}
+		}
+	}
+
+	var records []Record
+	query := db.Model(&Record{}).Select(columns)
+	err = addConstraints(query, f, t).Find(&records).Error
+	if err != nil {
+		err = errors.New("errore nella lettura dei dati: " + err.Error())
+		return
+	}
+
+	dp = make([]DataPoint, len(records))
+	for i := range records {
+		dp[i].Dt = float64(records[i].Dt)
+		for j, measure := range requestedMeasures {
+			dp[i].setValue(j, measureValue(&records[i], measure))
+		}
+	}
+	return
+}
+
 func initDB() (err error) {
 	// Assicuriamoci che la directory "data" esista
 	if err := os.MkdirAll(dataDir, os.ModePerm); err != nil {
@@ -201,6 +244,7 @@ func initDB() (err error) {
 		return errors.New("Errore nel parsing dello schema: " + err.Error())
 	}
 	dbMu.Lock()
+	recordSchema = s
 	measures = nil // Reset in case initDB is called multiple times
 	for _, field := range s.Fields {
 		if field.DBName == "" || field.DBName == "weather" || field.DBName == "dt" {
@@ -208,6 +252,9 @@ func initDB() (err error) {
 		}
 		measures = append(measures, field.DBName)
 	}
+	for _, d := range derivedMeasures {
+		measures = append(measures, d.Name)
+	}
 	dbMu.Unlock()
 
 	// Inizializzazione della zona
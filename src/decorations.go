@@ -0,0 +1,216 @@
+package src
+
+import (
+	"image/color"
+	"net/url"
+	"slices"
+
+	bh "github.com/birabittoh/bunnyhue"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+const (
+	stripHeight  = 6 // altezza della striscia delle condizioni, in punti
+	stripPadding = 0.08
+)
+
+// plotOptions raccoglie le decorazioni opzionali dei grafici con asse temporale.
+type plotOptions struct {
+	Night      bool
+	Conditions bool
+	Raw        bool // disattiva il sottocampionamento delle serie
+	Gaps       bool
+}
+
+func getPlotOptions(q url.Values) plotOptions {
+	return plotOptions{
+		Night:      q.Get("night") == "1",
+		Conditions: q.Get("conditions") == "1",
+		Raw:        q.Get("downsample") == "0",
+		Gaps:       q.Get("gaps") == "1",
+	}
+}
+
+// key restituisce una stringa da includere nelle chiavi di plotCache.
+func (o plotOptions) key() string {
+	k := ""
+	if o.Night {
+		k += "n"
+	}
+	if o.Conditions {
+		k += "c"
+	}
+	if o.Raw {
+		k += "r"
+	}
+	if o.Gaps {
+		k += "g"
+	}
+	return k
+}
+
+// points restituisce il numero di punti a cui ridurre le serie, oppure 0 se il sottocampionamento è disattivato.
+func (o plotOptions) points(pf plotFormat) int {
+	if o.Raw {
+		return 0
+	}
+	return pf.points(plotWidth)
+}
+
+// nightShading implementa plot.Plotter oscurando gli intervalli notturni per tutta l'altezza del grafico.
+type nightShading struct {
+	nights [][2]float64
+	color  color.Color
+}
+
+func (n *nightShading) Plot(c draw.Canvas, plt *plot.Plot) {
+	trX, _ := plt.Transforms(&c)
+	for _, night := range n.nights {
+		x0, x1 := trX(night[0]), trX(night[1])
+		c.FillPolygon(n.color, c.ClipPolygonXY([]vg.Point{{X: x0, Y: c.Min.Y}, {X: x0, Y: c.Max.Y}, {X: x1, Y: c.Max.Y}, {X: x1, Y: c.Min.Y}}))
+	}
+}
+
+// conditionStrip implementa plot.Plotter disegnando in basso una striscia colorata
+// in base alla condizione meteo principale di ogni campione.
+type conditionStrip struct {
+	xs     []float64
+	colors []color.Color
+}
+
+func (s *conditionStrip) Plot(c draw.Canvas, plt *plot.Plot) {
+	trX, _ := plt.Transforms(&c)
+	y0, y1 := c.Min.Y, c.Min.Y+vg.Points(stripHeight)
+	for i := range s.xs {
+		x0 := trX(s.xs[i])
+		x1 := x0
+		if i+1 < len(s.xs) {
+			x1 = trX(s.xs[i+1])
+		} else if i > 0 {
+			x1 = x0 + (x0 - trX(s.xs[i-1]))
+		}
+		c.FillPolygon(s.colors[i], c.ClipPolygonXY([]vg.Point{{X: x0, Y: y0}, {X: x0, Y: y1}, {X: x1, Y: y1}, {X: x1, Y: y0}}))
+	}
+}
+
+// conditionColor associa un colore della palette a ogni gruppo di condizioni meteo.
+func conditionColor(name string, palette *bh.Palette) color.Color {
+	switch name {
+	case "Clear":
+		return palette.Orange
+	case "Clouds":
+		return palette.Secondary
+	case "Drizzle":
+		return palette.Cyan
+	case "Rain":
+		return palette.Blue
+	case "Thunderstorm":
+		return palette.Purple
+	case "Snow":
+		return palette.Pink
+	}
+	return palette.Brown
+}
+
+// nightIntervals calcola gli intervalli notturni tra from e to a partire da alba e tramonto dei record.
+func nightIntervals(records []Record, from, to float64) (nights [][2]float64) {
+	type day struct{ sunrise, sunset float64 }
+	var days []day
+	for _, r := range records {
+		d := day{float64(r.Sunrise), float64(r.Sunset)}
+		if r.Sunrise == 0 || r.Sunset == 0 || slices.Contains(days, d) {
+			continue
+		}
+		days = append(days, d)
+	}
+	slices.SortFunc(days, func(a, b day) int { return int(a.sunrise - b.sunrise) })
+
+	start := from
+	for _, d := range days {
+		if d.sunrise > start {
+			nights = append(nights, [2]float64{start, min(d.sunrise, to)})
+		}
+		start = max(start, d.sunset)
+	}
+	if start < to && len(days) > 0 {
+		nights = append(nights, [2]float64{start, to})
+	}
+	return
+}
+
+// decorations sono le decorazioni richieste per un grafico temporale, con i record che servono a disegnarle.
+// Lo sfondo (notti e buchi nei dati) va aggiunto prima delle serie, così da non coprirle;
+// la striscia delle condizioni dopo, perché ha bisogno dell'intervallo dell'asse Y.
+// Un valore nil non aggiunge nulla.
+type decorations struct {
+	opts    plotOptions
+	f, t    *int64
+	records []Record
+	l       *Locale
+	palette *bh.Palette
+}
+
+func newDecorations(f, t *int64, opts plotOptions, l *Locale, palette *bh.Palette) (*decorations, error) {
+	if !opts.Night && !opts.Conditions && !opts.Gaps {
+		return nil, nil
+	}
+
+	records, err := getAllRecords(*f, *t)
+	if err != nil {
+		return nil, err
+	}
+	return &decorations{opts: opts, f: f, t: t, records: records, l: l, palette: palette}, nil
+}
+
+// background aggiunge l'ombreggiatura notturna e il tratteggio dei buchi nei dati.
+func (d *decorations) background(p *plot.Plot) {
+	if d == nil {
+		return
+	}
+
+	if d.opts.Night {
+		r, g, b, _ := d.palette.Primary.RGBA()
+		p.Add(&nightShading{
+			nights: nightIntervals(d.records, float64(*d.f), float64(*d.t)),
+			color:  color.NRGBA{R: uint8(r >> 8), G: uint8(g >> 8), B: uint8(b >> 8), A: 24},
+		})
+	}
+
+	if d.opts.Gaps {
+		bands := &gapBands{gaps: recordGaps(d.records, *d.f, *d.t), color: d.palette.Secondary}
+		p.Add(bands)
+		if len(bands.gaps) > 0 {
+			p.Legend.Add(d.l.T("No data"), bands)
+		}
+	}
+}
+
+// foreground aggiunge la striscia delle condizioni; va chiamata dopo aver aggiunto i dati.
+func (d *decorations) foreground(p *plot.Plot) {
+	if d == nil || !d.opts.Conditions {
+		return
+	}
+
+	// I colori dipendono dai nomi inglesi delle condizioni, la legenda usa quelli tradotti
+	strip := &conditionStrip{}
+	var seen []string
+	localized := d.l.localize(d.records)
+	for i, r := range d.records {
+		if len(r.Conditions) == 0 {
+			continue
+		}
+		name := r.Conditions[0].Name
+		strip.xs = append(strip.xs, float64(r.Dt))
+		strip.colors = append(strip.colors, conditionColor(name, d.palette))
+		if !slices.Contains(seen, name) {
+			seen = append(seen, name)
+			p.Legend.Add(localized[i].Conditions[0].Name, legendThumb{conditionColor(name, d.palette)})
+		}
+	}
+
+	// Lascia spazio in basso per la striscia
+	p.Y.Min -= (p.Y.Max - p.Y.Min) * stripPadding
+	p.Add(strip)
+}
@@ -0,0 +1,194 @@
+package src
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	bh "github.com/birabittoh/bunnyhue"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+)
+
+const (
+	degreeDaysBase    = 18.0
+	methodMean        = "mean"
+	methodIntegration = "integration"
+	dateFormat        = "2006-01-02"
+)
+
+type DegreeDay struct {
+	Date   string  `json:"date"`
+	HDD    float64 `json:"hdd"`
+	CDD    float64 `json:"cdd"`
+	CumHDD float64 `json:"cum_hdd"`
+	CumCDD float64 `json:"cum_cdd"`
+}
+
+type DegreeDays struct {
+	Base     float64     `json:"base"`
+	Unit     string      `json:"unit"`
+	Method   string      `json:"method"`
+	Days     []DegreeDay `json:"days"`
+	TotalHDD float64     `json:"total_hdd"`
+	TotalCDD float64     `json:"total_cdd"`
+}
+
+// parseBase interpreta la temperatura base, in °C o in °F con il suffisso "C" o "F" (es. "65F");
+// senza suffisso è espressa nell'unità di temperatura del sistema scelto.
+func parseBase(s string, us UnitSystem) (float64, error) {
+	if s == "" {
+		return degreeDaysBase, nil
+	}
+
+	s = strings.ToUpper(strings.TrimSpace(s))
+	fahrenheit := us.Temperature == "F"
+	if strings.HasSuffix(s, "F") || strings.HasSuffix(s, "C") {
+		fahrenheit = strings.HasSuffix(s, "F")
+		s = s[:len(s)-1]
+	}
+
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, errors.New("temperatura base non valida: " + s)
+	}
+	if fahrenheit {
+		v = (v - 32) * 5 / 9
+	}
+	return v, nil
+}
+
+func dayStart(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// computeDegreeDays calcola i gradi giorno di riscaldamento (HDD) e raffrescamento (CDD) per ogni giorno.
+// Con il metodo "mean" si usa la media tra minima e massima del giorno, con "integration" si integra
+// la differenza dalla base sui singoli intervalli tra i campioni.
+func computeDegreeDays(dp []DataPoint, base float64, method string) (dd DegreeDays, err error) {
+	dd = DegreeDays{Base: base, Method: method, Days: []DegreeDay{}}
+
+	var days []time.Time
+	byDay := map[time.Time][]DataPoint{}
+	for _, p := range dp {
+		d := dayStart(localTime(int64(p.Dt)))
+		if _, ok := byDay[d]; !ok {
+			days = append(days, d)
+		}
+		byDay[d] = append(byDay[d], p)
+	}
+
+	var hdd, cdd float64
+	for i, d := range days {
+		switch method {
+		case methodMean:
+			hdd, cdd = degreeDaysMean(byDay[d], base)
+		case methodIntegration:
+			var next *DataPoint
+			if i+1 < len(days) {
+				next = &byDay[days[i+1]][0]
+			}
+			hdd, cdd = degreeDaysIntegration(byDay[d], next, base)
+		default:
+			err = errors.New("metodo non supportato: " + method)
+			return
+		}
+
+		dd.TotalHDD += hdd
+		dd.TotalCDD += cdd
+		dd.Days = append(dd.Days, DegreeDay{
+			Date:   d.Format(dateFormat),
+			HDD:    hdd,
+			CDD:    cdd,
+			CumHDD: dd.TotalHDD,
+			CumCDD: dd.TotalCDD,
+		})
+	}
+	return
+}
+
+func degreeDaysMean(dp []DataPoint, base float64) (hdd, cdd float64) {
+	tMin, tMax := dp[0].Value0, dp[0].Value0
+	for _, p := range dp {
+		tMin = min(tMin, p.Value0)
+		tMax = max(tMax, p.Value0)
+	}
+
+	mean := (tMin + tMax) / 2
+	return max(base-mean, 0), max(mean-base, 0)
+}
+
+// degreeDaysIntegration integra con il metodo dei trapezi; next è il primo campione del giorno successivo,
+// usato per chiudere l'ultimo intervallo. Gli intervalli più lunghi di tre esecuzioni del cron vengono ignorati.
+func degreeDaysIntegration(dp []DataPoint, next *DataPoint, base float64) (hdd, cdd float64) {
+	if next != nil {
+		dp = append(dp[:len(dp):len(dp)], *next)
+	}
+
+	for i := 1; i < len(dp); i++ {
+		dt := dp[i].Dt - dp[i-1].Dt
+		if dt <= 0 || isGap(dt) {
+			continue
+		}
+
+		mean := (dp[i].Value0 + dp[i-1].Value0) / 2
+		frac := dt / (24 * 60 * 60)
+		hdd += max(base-mean, 0) * frac
+		cdd += max(mean-base, 0) * frac
+	}
+	return
+}
+
+// convert esprime la base e i gradi giorno nell'unità di temperatura del sistema.
+func (dd *DegreeDays) convert(us UnitSystem) {
+	dd.Unit = us.Unit("temp")
+	dd.Base = us.Convert("temp", dd.Base)
+	dd.TotalHDD, dd.TotalCDD = us.ConvertDelta("temp", dd.TotalHDD), us.ConvertDelta("temp", dd.TotalCDD)
+	for i := range dd.Days {
+		d := &dd.Days[i]
+		d.HDD, d.CDD = us.ConvertDelta("temp", d.HDD), us.ConvertDelta("temp", d.CDD)
+		d.CumHDD, d.CumCDD = us.ConvertDelta("temp", d.CumHDD), us.ConvertDelta("temp", d.CumCDD)
+	}
+}
+
+func getDegreeDays(f, t *int64, base float64, method string, us UnitSystem) (dd DegreeDays, err error) {
+	dp, err := getDataPoints([]string{"temp"}, f, t)
+	if err != nil {
+		err = errors.New("errore nella lettura dei dati: " + err.Error())
+		return
+	}
+	dd, err = computeDegreeDays(dp, base, method)
+	if err == nil {
+		dd.convert(us)
+	}
+	return
+}
+
+func plotDegreeDays(f, t *int64, base float64, method string, us UnitSystem, l *Locale, dec *decorations, palette *bh.Palette) (p *plot.Plot, err error) {
+	dd, err := getDegreeDays(f, t, base, method, us)
+	if err != nil {
+		return
+	}
+
+	hPts := make(plotter.XYs, len(dd.Days))
+	cPts := make(plotter.XYs, len(dd.Days))
+	for i, d := range dd.Days {
+		day, _ := time.ParseInLocation(dateFormat, d.Date, appLocation)
+		x := float64(day.Unix())
+
+		hPts[i].X, hPts[i].Y = x, d.CumHDD
+		cPts[i].X, cPts[i].Y = x, d.CumCDD
+	}
+
+	p = newPlot(l, palette)
+	dec.background(p)
+
+	err = addLines(p, hPts, palette.Blue, false, "HDD")
+	if err != nil {
+		return
+	}
+	err = addLines(p, cPts, palette.Red, false, "CDD")
+	return
+}
@@ -0,0 +1,134 @@
+package src
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestComputeDegreeDays(t *testing.T) {
+	cronInterval = 1800
+
+	// Due giorni a temperatura costante: 10°C e poi 24°C
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, appLocation)
+	var dp []DataPoint
+	for i := 0; i < 96; i++ {
+		temp := 10.0
+		if i >= 48 {
+			temp = 24
+		}
+		dp = append(dp, DataPoint{Dt: float64(start.Add(time.Duration(i) * 30 * time.Minute).Unix()), Value0: temp})
+	}
+
+	tests := []struct {
+		method   string
+		hdd, cdd float64
+	}{
+		{methodMean, 8, 6},
+		// L'intervallo a cavallo della mezzanotte ha media 17°C
+		{methodIntegration, 8 + 1.0/48 - 8.0/48, 6 - 6.0/48},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			dd, err := computeDegreeDays(dp, 18, tt.method)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(dd.Days) != 2 {
+				t.Fatalf("expected 2 days, got %d", len(dd.Days))
+			}
+			if math.Abs(dd.TotalHDD-tt.hdd) > 1e-9 || math.Abs(dd.TotalCDD-tt.cdd) > 1e-9 {
+				t.Errorf("got HDD %.4f CDD %.4f, want HDD %.4f CDD %.4f", dd.TotalHDD, dd.TotalCDD, tt.hdd, tt.cdd)
+			}
+			if dd.Days[1].CumHDD != dd.TotalHDD {
+				t.Errorf("cumulative HDD %.4f differs from total %.4f", dd.Days[1].CumHDD, dd.TotalHDD)
+			}
+		})
+	}
+
+	if _, err := computeDegreeDays(dp, 18, "bogus"); err == nil {
+		t.Error("expected an error for an unknown method")
+	}
+}
+
+func TestParseBase(t *testing.T) {
+	metric, imperial := unitSystems["metric"], unitSystems["imperial"]
+	tests := []struct {
+		in   string
+		us   UnitSystem
+		want float64
+	}{
+		{"", metric, 18}, {"15.5", metric, 15.5}, {"65F", metric, 18.333333333333332}, {"20c", metric, 20},
+		{"", imperial, 18}, {"65", imperial, 18.333333333333332}, {"20C", imperial, 20},
+	}
+	for _, tt := range tests {
+		got, err := parseBase(tt.in, tt.us)
+		if err != nil || math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("parseBase(%q, %s) = %v, %v; want %v", tt.in, tt.us, got, err, tt.want)
+		}
+	}
+	if _, err := parseBase("warm", metric); err == nil {
+		t.Error("expected an error for an invalid base")
+	}
+}
+
+func TestGetDegreeDaysParams(t *testing.T) {
+	tests := map[string]string{"": methodMean, "method=mean": methodMean, "method=integration": methodIntegration, "method=bogus": ""}
+	for query, want := range tests {
+		_, method, err := getDegreeDaysParams(httptest.NewRequest(http.MethodGet, "/api/degree-days?"+query, nil), unitSystems["metric"])
+		if (err != nil) != (want == "") || method != want && want != "" {
+			t.Errorf("%q: got %q, %v; want %q", query, method, err, want)
+		}
+	}
+}
+
+func TestDegreeDaysConvert(t *testing.T) {
+	dd := DegreeDays{Base: 18, TotalHDD: 10, Days: []DegreeDay{{HDD: 10, CumHDD: 10}}}
+	dd.convert(unitSystems["imperial"])
+	if dd.Unit != "°F" || math.Abs(dd.Base-64.4) > 1e-9 {
+		t.Errorf("got base %v%s, want 64.4°F", dd.Base, dd.Unit)
+	}
+	if math.Abs(dd.TotalHDD-18) > 1e-9 || math.Abs(dd.Days[0].HDD-18) > 1e-9 || math.Abs(dd.Days[0].CumHDD-18) > 1e-9 {
+		t.Errorf("degree-days not converted as a difference: %+v", dd)
+	}
+}
+
+func TestAgricultureConvert(t *testing.T) {
+	a := Agriculture{Season: Season{Base: 10, ChillThreshold: 7}, TotalGDD: 5, Days: []AgricultureDay{{TMin: 0, TMax: 20, GDD: 5, CumGDD: 5, ChillHours: 3}}}
+	a.convert(unitSystems["imperial"])
+	d := a.Days[0]
+	if a.Unit != "°F" || a.Base != 50 || math.Abs(a.ChillThreshold-44.6) > 1e-9 {
+		t.Errorf("got base %v and chill threshold %v %s", a.Base, a.ChillThreshold, a.Unit)
+	}
+	if d.TMin != 32 || d.TMax != 68 || d.GDD != 9 || d.CumGDD != 9 || a.TotalGDD != 9 || d.ChillHours != 3 {
+		t.Errorf("unexpected converted day: %+v, total %v", d, a.TotalGDD)
+	}
+}
+
+func TestComputeAgricultureFrosts(t *testing.T) {
+	cronInterval = 3600
+
+	// Stagione dell'emisfero sud, dal 1° ottobre: gelate a novembre e a maggio
+	s := Season{Start: "10-01", Base: 10, ChillThreshold: 7}
+	var dp []DataPoint
+	for _, d := range []time.Time{
+		time.Date(2024, 10, 15, 6, 0, 0, 0, appLocation),
+		time.Date(2024, 11, 10, 6, 0, 0, 0, appLocation),
+		time.Date(2025, 5, 20, 6, 0, 0, 0, appLocation),
+		time.Date(2025, 6, 5, 6, 0, 0, 0, appLocation),
+	} {
+		tMin := 5.0
+		if d.Month() == time.November || d.Month() == time.May {
+			tMin = -1
+		}
+		dp = append(dp, DataPoint{Dt: float64(d.Unix()), Value0: 15, Value1: tMin})
+	}
+
+	a := computeAgriculture(dp, s, 2024)
+	if a.LastFrost != "2024-11-10" || a.FirstFrost != "2025-05-20" {
+		t.Errorf("got last frost %q and first frost %q", a.LastFrost, a.FirstFrost)
+	}
+}
@@ -0,0 +1,151 @@
+package src
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"slices"
+
+	"gorm.io/gorm/schema"
+)
+
+// derivedMeasure è una misura calcolata a partire dalle colonne salvate nel database.
+type derivedMeasure struct {
+	Name    string
+	Columns []string
+	Compute func(r *Record) float64
+}
+
+var (
+	recordSchema *schema.Schema
+
+	derivedMeasures = []derivedMeasure{
+		{
+			Name:    "dew_point",
+			Columns: []string{"temp", "humidity"},
+			Compute: func(r *Record) float64 { return dewPoint(r.Temp, float64(r.Humidity)) },
+		},
+		{
+			Name:    "absolute_humidity",
+			Columns: []string{"temp", "humidity"},
+			Compute: func(r *Record) float64 { return absoluteHumidity(r.Temp, float64(r.Humidity)) },
+		},
+		{
+			Name:    "heat_index",
+			Columns: []string{"temp", "humidity"},
+			Compute: func(r *Record) float64 { return heatIndex(r.Temp, float64(r.Humidity)) },
+		},
+		{
+			Name:    "wind_chill",
+			Columns: []string{"temp", "wind_speed"},
+			Compute: func(r *Record) float64 { return windChill(r.Temp, r.WindSpeed) },
+		},
+		{
+			Name:    "humidex",
+			Columns: []string{"temp", "humidity"},
+			Compute: func(r *Record) float64 { return humidex(r.Temp, dewPoint(r.Temp, float64(r.Humidity))) },
+		},
+		{
+			Name:    "apparent_temp",
+			Columns: []string{"temp", "humidity", "wind_speed"},
+			Compute: func(r *Record) float64 { return apparentTemperature(r.Temp, float64(r.Humidity), r.WindSpeed) },
+		},
+	}
+)
+
+func getDerivedMeasure(name string) (derivedMeasure, bool) {
+	i := slices.IndexFunc(derivedMeasures, func(d derivedMeasure) bool { return d.Name == name })
+	if i < 0 {
+		return derivedMeasure{}, false
+	}
+	return derivedMeasures[i], true
+}
+
+func isDerived(name string) bool {
+	_, ok := getDerivedMeasure(name)
+	return ok
+}
+
+// measureValue restituisce il valore di una misura (salvata o derivata) per un record.
+func measureValue(r *Record, measure string) float64 {
+	if d, ok := getDerivedMeasure(measure); ok {
+		return d.Compute(r)
+	}
+
+	field := recordSchema.LookUpField(measure)
+	if field == nil {
+		return 0
+	}
+
+	v, _ := field.ValueOf(context.Background(), reflect.ValueOf(r).Elem())
+	switch n := v.(type) {
+	case float64:
+		return n
+	case int:
+		return float64(n)
+	case int64:
+		return float64(n)
+	}
+	return 0
+}
+
+// ------------------------
+// FORMULE
+// ------------------------
+
+// dewPoint calcola il punto di rugiada (°C) con la formula di Magnus.
+func dewPoint(t, rh float64) float64 {
+	const b, c = 17.62, 243.12
+	rh = max(rh, 1) // evita log(0)
+	g := math.Log(rh/100) + b*t/(c+t)
+	return c * g / (b - g)
+}
+
+// absoluteHumidity calcola l'umidità assoluta in g/m³.
+func absoluteHumidity(t, rh float64) float64 {
+	return 6.112 * math.Exp(17.67*t/(t+243.5)) * rh * 2.1674 / (273.15 + t)
+}
+
+// heatIndex calcola l'indice di calore (°C) con l'algoritmo del NWS (Rothfusz + correzioni).
+func heatIndex(t, rh float64) float64 {
+	f := t*9/5 + 32
+
+	hi := 0.5 * (f + 61 + (f-68)*1.2 + rh*0.094)
+	if (hi+f)/2 >= 80 {
+		hi = -42.379 + 2.04901523*f + 10.14333127*rh -
+			0.22475541*f*rh - 0.00683783*f*f -
+			0.05481717*rh*rh + 0.00122874*f*f*rh +
+			0.00085282*f*rh*rh - 0.00000199*f*f*rh*rh
+
+		if rh < 13 && f >= 80 && f <= 112 {
+			hi -= (13 - rh) / 4 * math.Sqrt((17-math.Abs(f-95))/17)
+		} else if rh > 85 && f >= 80 && f <= 87 {
+			hi += (rh - 85) / 10 * (87 - f) / 5
+		}
+	}
+
+	return (hi - 32) * 5 / 9
+}
+
+// windChill calcola la temperatura percepita per effetto del vento (°C), con il vento in m/s.
+// Fuori dal dominio di validità della formula restituisce la temperatura dell'aria.
+func windChill(t, ws float64) float64 {
+	v := ws * 3.6
+	if t > 10 || v <= 4.8 {
+		return t
+	}
+	p := math.Pow(v, 0.16)
+	return 13.12 + 0.6215*t - 11.37*p + 0.3965*t*p
+}
+
+// humidex calcola l'indice humidex canadese a partire da temperatura e punto di rugiada (°C).
+func humidex(t, td float64) float64 {
+	e := 6.11 * math.Exp(5417.7530*(1/273.16-1/(273.15+td)))
+	return t + 0.5555*(e-10)
+}
+
+// apparentTemperature calcola la temperatura apparente di Steadman (versione BoM), con il vento in m/s.
+func apparentTemperature(t, rh, ws float64) float64 {
+	e := rh / 100 * 6.105 * math.Exp(17.27*t/(237.7+t))
+	return t + 0.33*e - 0.70*ws - 4.00
+}
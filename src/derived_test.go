@@ -0,0 +1,52 @@
+package src
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDerivedFormulas(t *testing.T) {
+	tests := []struct {
+		name string
+		f    func() float64
+		want float64
+		tol  float64
+	}{
+		// Punto di rugiada (Magnus)
+		{"dew point 25°C 60%", func() float64 { return dewPoint(25, 60) }, 16.7, 0.1},
+		{"dew point 30°C 80%", func() float64 { return dewPoint(30, 80) }, 26.2, 0.1},
+		{"dew point 10°C 100%", func() float64 { return dewPoint(10, 100) }, 10, 0.01},
+
+		// Densità del vapore saturo
+		{"absolute humidity 20°C 100%", func() float64 { return absoluteHumidity(20, 100) }, 17.3, 0.1},
+		{"absolute humidity 30°C 100%", func() float64 { return absoluteHumidity(30, 100) }, 30.4, 0.1},
+
+		// Tabella NWS (90°F/50% -> 95°F, 100°F/40% -> 109°F, 80°F/40% -> 80°F)
+		{"heat index 90°F 50%", func() float64 { return heatIndex(32.22, 50) }, 35, 0.5},
+		{"heat index 100°F 40%", func() float64 { return heatIndex(37.78, 40) }, 42.78, 0.5},
+		{"heat index 80°F 40%", func() float64 { return heatIndex(26.67, 40) }, 26.67, 0.5},
+
+		// Tabella Environment Canada (vento in km/h convertito in m/s)
+		{"wind chill -10°C 30km/h", func() float64 { return windChill(-10, 30/3.6) }, -19.5, 0.1},
+		{"wind chill -20°C 20km/h", func() float64 { return windChill(-20, 20/3.6) }, -30, 0.5},
+		{"wind chill 5°C 10km/h", func() float64 { return windChill(5, 10/3.6) }, 2.7, 0.1},
+		{"wind chill out of range", func() float64 { return windChill(20, 10) }, 20, 0},
+
+		// Tabella Environment Canada (temperatura, punto di rugiada)
+		{"humidex 30°C td 15°C", func() float64 { return humidex(30, 15) }, 34, 0.5},
+		{"humidex 35°C td 25°C", func() float64 { return humidex(35, 25) }, 47, 0.5},
+
+		// Steadman, versione Bureau of Meteorology
+		{"apparent temp 25°C 50% calm", func() float64 { return apparentTemperature(25, 50, 0) }, 26.2, 0.1},
+		{"apparent temp 10°C 80% 5m/s", func() float64 { return apparentTemperature(10, 80, 5) }, 5.7, 0.1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.f()
+			if math.Abs(got-tt.want) > tt.tol {
+				t.Errorf("got %.3f, want %.3f ± %.2f", got, tt.want, tt.tol)
+			}
+		})
+	}
+}
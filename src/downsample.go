@@ -0,0 +1,65 @@
+package src
+
+import (
+	"math"
+
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg"
+)
+
+// downsample riduce una serie a circa threshold punti con l'algoritmo Largest-Triangle-Three-Buckets:
+// per ogni intervallo sceglie il punto che forma il triangolo più grande con quello scelto
+// nell'intervallo precedente e con la media del successivo, conservando picchi e minimi.
+// Con threshold pari a 0 o non inferiore al numero di punti la serie viene restituita invariata.
+func downsample(pts plotter.XYs, threshold int) plotter.XYs {
+	if threshold <= 0 || threshold >= len(pts) {
+		return pts
+	}
+	if threshold < 3 {
+		threshold = 3
+	}
+
+	out := make(plotter.XYs, 0, threshold)
+	out = append(out, pts[0])
+
+	bucket := float64(len(pts)-2) / float64(threshold-2)
+	a := 0
+	for i := range threshold - 2 {
+		start := int(float64(i)*bucket) + 1
+		end := int(float64(i+1)*bucket) + 1
+
+		// Media dell'intervallo successivo
+		nextEnd := min(int(float64(i+2)*bucket)+1, len(pts))
+		var avgX, avgY float64
+		for _, p := range pts[end:nextEnd] {
+			avgX += p.X
+			avgY += p.Y
+		}
+		n := float64(nextEnd - end)
+		avgX /= n
+		avgY /= n
+
+		best, area := start, -1.0
+		for j := start; j < end; j++ {
+			s := math.Abs((pts[a].X-avgX)*(pts[j].Y-pts[a].Y) - (pts[a].X-pts[j].X)*(avgY-pts[a].Y))
+			if s > area {
+				best, area = j, s
+			}
+		}
+
+		out = append(out, pts[best])
+		a = best
+	}
+
+	return append(out, pts[len(pts)-1])
+}
+
+// points restituisce il numero di punti a cui ridurre le serie, uno per pixel della larghezza finale.
+func (pf plotFormat) points(w vg.Length) int {
+	w, _ = pf.size(w, plotHeight)
+	dpi := 96
+	if pf.Name == "png" {
+		dpi = pf.DPI
+	}
+	return int(w / vg.Inch * vg.Length(dpi))
+}
@@ -0,0 +1,72 @@
+package src
+
+import (
+	"math"
+	"testing"
+
+	bh "github.com/birabittoh/bunnyhue"
+	"gonum.org/v1/plot/plotter"
+)
+
+// sineSeries genera n campioni a intervalli di 10 minuti con un picco isolato a metà.
+func sineSeries(n int) plotter.XYs {
+	pts := make(plotter.XYs, n)
+	for i := range pts {
+		pts[i].X = float64(i * 600)
+		pts[i].Y = 10 + 8*math.Sin(float64(i)/72*math.Pi)
+	}
+	pts[n/2].Y = 100
+	return pts
+}
+
+func TestDownsample(t *testing.T) {
+	pts := sineSeries(10000)
+
+	out := downsample(pts, 500)
+	if len(out) != 500 {
+		t.Fatalf("got %d points, want 500", len(out))
+	}
+	if out[0] != pts[0] || out[len(out)-1] != pts[len(pts)-1] {
+		t.Error("first and last points must be kept")
+	}
+	for i := 1; i < len(out); i++ {
+		if out[i].X <= out[i-1].X {
+			t.Fatalf("points are not ordered at %d", i)
+		}
+	}
+	_, _, _, ymax := plotter.XYRange(out)
+	if ymax != 100 {
+		t.Errorf("peak was lost, max is %v", ymax)
+	}
+
+	if got := downsample(pts, 0); len(got) != len(pts) {
+		t.Errorf("threshold 0 should disable downsampling, got %d points", len(got))
+	}
+	if got := downsample(pts[:100], 500); len(got) != 100 {
+		t.Errorf("short series should be unchanged, got %d points", len(got))
+	}
+}
+
+func benchmarkPlotSVG(b *testing.B, points int) {
+	pts := sineSeries(20000)
+
+	var size int
+	for b.Loop() {
+		p := newPlot(newDefaultLocale(), &bh.Dark)
+		if err := addLines(p, downsample(pts, points), bh.Dark.Primary, false, "Temp"); err != nil {
+			b.Fatal(err)
+		}
+		svg, err := getPlotImage(p, plotWidth, plotHeight, plotFormats[0])
+		if err != nil {
+			b.Fatal(err)
+		}
+		size = len(svg)
+	}
+	b.ReportMetric(float64(size), "bytes/svg")
+}
+
+func BenchmarkPlotSVGRaw(b *testing.B) { benchmarkPlotSVG(b, 0) }
+
+func BenchmarkPlotSVGDownsampled(b *testing.B) {
+	benchmarkPlotSVG(b, plotFormats[0].points(plotWidth))
+}
@@ -0,0 +1,209 @@
+package src
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gonum.org/v1/plot/vg"
+)
+
+const (
+	defaultDPI = 96
+	minDPI     = 36
+	maxDPI     = 600
+
+	// Limiti delle dimensioni richieste dal client, in pixel CSS (1/96 di pollice)
+	minPlotWidth  = 200
+	maxPlotWidth  = 2400
+	minPlotHeight = 150
+	maxPlotHeight = 1600
+)
+
+// plotFormat descrive il formato e le dimensioni di output di un grafico.
+type plotFormat struct {
+	Name        string
+	ContentType string
+	DPI         int         // usato solo per i formati raster
+	Background  color.Color // sfondo dei formati diversi da SVG, che non possono ereditarlo dalla pagina
+	Width       int         // in pixel CSS, 0 per la dimensione predefinita
+	Height      int         // in pixel CSS, 0 per la dimensione predefinita
+}
+
+// Risoluzione predefinita dei formati raster, letta da PLOT_DPI all'avvio
+var plotDPI = defaultDPI
+
+var plotFormats = []plotFormat{
+	{Name: "svg", ContentType: "image/svg+xml"},
+	{Name: "png", ContentType: "image/png"},
+	{Name: "pdf", ContentType: "application/pdf"},
+	{Name: "eps", ContentType: "application/postscript"},
+}
+
+// key restituisce una stringa da includere nelle chiavi delle cache dei grafici.
+func (pf plotFormat) key() string {
+	k := pf.Name
+	if pf.Name == "png" {
+		k += "@" + strconv.Itoa(pf.DPI)
+	}
+	if pf.Width > 0 || pf.Height > 0 {
+		k += fmt.Sprintf("|%dx%d", pf.Width, pf.Height)
+	}
+	return k
+}
+
+// size restituisce le dimensioni del grafico, partendo da quelle predefinite w e h.
+// Se il client ne specifica una sola, l'altra viene ricavata mantenendo le proporzioni.
+func (pf plotFormat) size(w, h vg.Length) (vg.Length, vg.Length) {
+	px := func(v int) vg.Length { return vg.Length(v) * vg.Inch / 96 }
+	switch {
+	case pf.Width > 0 && pf.Height > 0:
+		return px(pf.Width), px(pf.Height)
+	case pf.Width > 0:
+		return px(pf.Width), h * px(pf.Width) / w
+	case pf.Height > 0:
+		return w * px(pf.Height) / h, px(pf.Height)
+	}
+	return w, h
+}
+
+// getDimension legge un parametro di dimensione, restituendo 0 se assente.
+func getDimension(q url.Values, name string, lo, hi int) (int, error) {
+	v := q.Get(name)
+	if v == "" {
+		return 0, nil
+	}
+	d, err := strconv.Atoi(v)
+	if err != nil || d < lo || d > hi {
+		return 0, fmt.Errorf("dimensione %s non valida, deve essere compresa tra %d e %d", name, lo, hi)
+	}
+	return d, nil
+}
+
+func findPlotFormat(match func(plotFormat) bool) (plotFormat, bool) {
+	i := slices.IndexFunc(plotFormats, match)
+	if i < 0 {
+		return plotFormat{}, false
+	}
+	return plotFormats[i], true
+}
+
+// acceptValues restituisce i valori di un header di negoziazione (Accept, Accept-Language)
+// ordinati per peso q decrescente, escludendo quelli con peso nullo.
+func acceptValues(header string) (values []string) {
+	type candidate struct {
+		value string
+		q     float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(header, ",") {
+		value, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			q, err = strconv.ParseFloat(v, 64)
+			if err != nil || q <= 0 {
+				continue
+			}
+		}
+		candidates = append(candidates, candidate{value, q})
+	}
+	slices.SortStableFunc(candidates, func(a, b candidate) int {
+		switch {
+		case a.q > b.q:
+			return -1
+		case a.q < b.q:
+			return 1
+		}
+		return 0
+	})
+
+	for _, c := range candidates {
+		values = append(values, c.value)
+	}
+	return
+}
+
+// negotiatePlotFormat sceglie il formato in base all'header Accept, rispettando i pesi q.
+// Se nessun tipo è supportato viene usato SVG.
+func negotiatePlotFormat(accept string) plotFormat {
+	for _, mediaType := range acceptValues(accept) {
+		pf, ok := findPlotFormat(func(pf plotFormat) bool { return pf.ContentType == mediaType })
+		if ok {
+			return pf
+		}
+		if mediaType == "image/*" || mediaType == "*/*" {
+			break
+		}
+	}
+	return plotFormats[0]
+}
+
+// parseDPI interpreta una risoluzione in dpi, controllandone i limiti.
+func parseDPI(s string) (int, error) {
+	dpi, err := strconv.Atoi(s)
+	if err != nil || dpi < minDPI || dpi > maxDPI {
+		return 0, fmt.Errorf("risoluzione non valida, deve essere compresa tra %d e %d dpi", minDPI, maxDPI)
+	}
+	return dpi, nil
+}
+
+// loadPlotDPI legge da PLOT_DPI la risoluzione predefinita dei formati raster.
+func loadPlotDPI() error {
+	v := os.Getenv("PLOT_DPI")
+	if v == "" {
+		return nil
+	}
+	dpi, err := parseDPI(v)
+	if err != nil {
+		return errors.New("PLOT_DPI non valida: " + err.Error())
+	}
+	plotDPI = dpi
+	return nil
+}
+
+// getPlotFormat legge il formato richiesto dal parametro format o, in sua assenza, dall'header Accept.
+// La risoluzione dei formati raster si imposta con il parametro dpi (predefinita PLOT_DPI, ignorato negli altri formati),
+// le dimensioni con w e h.
+func getPlotFormat(r *http.Request) (pf plotFormat, err error) {
+	q := r.URL.Query()
+	if name := q.Get("format"); name != "" {
+		var ok bool
+		pf, ok = findPlotFormat(func(pf plotFormat) bool { return pf.Name == name })
+		if !ok {
+			err = errors.New("formato non supportato: " + name)
+			return
+		}
+	} else {
+		pf = negotiatePlotFormat(r.Header.Get("Accept"))
+	}
+
+	pf.Background = getPalette(q).Background
+
+	if pf.Name == "png" {
+		pf.DPI = plotDPI
+		if dpi := q.Get("dpi"); dpi != "" {
+			pf.DPI, err = parseDPI(dpi)
+			if err != nil {
+				return
+			}
+		}
+	}
+
+	pf.Width, err = getDimension(q, "w", minPlotWidth, maxPlotWidth)
+	if err != nil {
+		return
+	}
+	pf.Height, err = getDimension(q, "h", minPlotHeight, maxPlotHeight)
+	return
+}
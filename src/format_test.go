@@ -0,0 +1,61 @@
+package src
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNegotiatePlotFormat(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", "svg"},
+		{"image/png", "png"},
+		{"application/pdf, image/png;q=0.5", "pdf"},
+		{"image/svg+xml;q=0.2, image/png;q=0.8", "png"},
+		{"image/avif,image/webp,image/apng,image/svg+xml,image/*,*/*;q=0.8", "svg"},
+		{"image/*, application/postscript;q=0.5", "svg"},
+		{"text/html, application/postscript;q=0.5", "eps"},
+		{"image/png;q=0", "svg"},
+	}
+
+	for _, tt := range tests {
+		if got := negotiatePlotFormat(tt.accept).Name; got != tt.want {
+			t.Errorf("Accept %q: got %s, want %s", tt.accept, got, tt.want)
+		}
+	}
+}
+
+func TestLoadPlotDPI(t *testing.T) {
+	defer func() { plotDPI = defaultDPI }()
+
+	t.Setenv("PLOT_DPI", "abc")
+	if err := loadPlotDPI(); err == nil || plotDPI != defaultDPI {
+		t.Errorf("got %v, dpi %d; want an error and dpi %d", err, plotDPI, defaultDPI)
+	}
+
+	t.Setenv("PLOT_DPI", "150")
+	if err := loadPlotDPI(); err != nil || plotDPI != 150 {
+		t.Errorf("got %v, dpi %d; want dpi 150", err, plotDPI)
+	}
+
+	tests := []struct {
+		query string
+		dpi   int
+		err   bool
+	}{
+		{"format=png", 150, false},
+		{"format=png&dpi=300", 300, false},
+		{"format=png&dpi=1000", 0, true},
+		{"format=svg&dpi=1000", 0, false},
+		{"format=pdf", 0, false},
+	}
+	for _, tt := range tests {
+		pf, err := getPlotFormat(httptest.NewRequest(http.MethodGet, "/api/temp?"+tt.query, nil))
+		if (err != nil) != tt.err || !tt.err && pf.DPI != tt.dpi {
+			t.Errorf("%s: got dpi %d, %v; want %d", tt.query, pf.DPI, err, tt.dpi)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"image/color"
 	"log"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
@@ -17,6 +18,7 @@ import (
 
 var (
 	directions  = []string{"↑", "↗", "→", "↘", "↓", "↙", "←", "↖"}
+	compass     = []string{"N", "NE", "E", "SE", "S", "SW", "W", "NW"}
 	percentages = []string{"○", "◔", "◑", "◕", "●"}
 	current     *openweathermap.CurrentWeatherData
 	funcMu      sync.RWMutex
@@ -139,8 +141,9 @@ func getHex(c color.Color) string {
 	return fmt.Sprintf("#%02x%02x%02x", uint8(r>>8), uint8(g>>8), uint8(b>>8))
 }
 
-func formatTimestamp(timestamp int64) string {
-	return time.Since(time.Unix(timestamp, 0)).Round(time.Second).String()
+// localTime converte un timestamp Unix nell'ora del fuso orario dell'applicazione.
+func localTime(timestamp int64) time.Time {
+	return time.Unix(timestamp, 0).In(appLocation)
 }
 
 func getFavicon(r Record) string {
@@ -162,7 +165,12 @@ func getWindDirection(deg float64) string {
 		return ""
 	}
 
-	return directions[int((deg+22.5)/45)%8]
+	return directions[windSector(deg)]
+}
+
+// windSector restituisce l'ottante della rosa dei venti corrispondente alla direzione, da 0 (N) a 7 (NW).
+func windSector(deg float64) int {
+	return int((deg+22.5)/45) % 8
 }
 
 func formatPercent(v int) string {
@@ -176,3 +184,39 @@ func getEnvDefault(key, def string) string {
 	}
 	return def
 }
+
+// PlotImage raccoglie i dati per il template "plotPicture".
+type PlotImage struct {
+	URL      string
+	LightURL string // con il tema automatico, il grafico da usare se il browser preferisce i colori chiari
+	Alt      string
+}
+
+// newPlotImage prepara un grafico per il template; con theme=auto il grafico viene generato
+// sia con il tema predefinito sia con quello chiaro, e il browser sceglie in base a prefers-color-scheme.
+func newPlotImage(plotURL, alt string) PlotImage {
+	pi := PlotImage{URL: plotURL, Alt: alt}
+	u, err := url.Parse(plotURL)
+	if err != nil || u.Query().Get("theme") != themeAuto {
+		return pi
+	}
+
+	withTheme := func(theme string) string {
+		q := u.Query()
+		q.Set("theme", theme)
+		v := *u
+		v.RawQuery = q.Encode()
+		return v.String()
+	}
+	pi.URL, pi.LightURL = withTheme(""), withTheme("light")
+	return pi
+}
+
+// withSize aggiunge all'URL di un grafico la larghezza richiesta, in pixel CSS.
+func withSize(url string, w int) string {
+	sep := "?"
+	if strings.Contains(url, "?") {
+		sep = "&"
+	}
+	return url + sep + "w=" + strconv.Itoa(w)
+}
@@ -0,0 +1,143 @@
+package src
+
+import (
+	"errors"
+	"image/color"
+	"slices"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+const (
+	gapFactor  = 3 // un intervallo tra due campioni più lungo di gapFactor*cronInterval è un buco nei dati
+	hatchSpace = 6 // distanza tra le linee del tratteggio, in punti
+)
+
+// Gap descrive un intervallo senza dati tra due campioni consecutivi.
+type Gap struct {
+	From int64 `json:"from"`
+	To   int64 `json:"to"`
+}
+
+// isGap indica se due campioni distanti dt secondi sono separati da un buco nei dati.
+func isGap(dt float64) bool {
+	return dt > float64(gapFactor*cronInterval)
+}
+
+// findGaps restituisce i buchi tra i timestamp ordinati dts.
+func findGaps(dts []float64) (gaps []Gap) {
+	gaps = []Gap{}
+	for i := 1; i < len(dts); i++ {
+		if isGap(dts[i] - dts[i-1]) {
+			gaps = append(gaps, Gap{From: int64(dts[i-1]), To: int64(dts[i])})
+		}
+	}
+	return
+}
+
+// recordGaps restituisce i buchi nei dati tra from e to, compresi quelli all'inizio e alla fine dell'intervallo.
+func recordGaps(records []Record, from, to int64) []Gap {
+	dts := []float64{float64(from)}
+	for _, r := range records {
+		dts = append(dts, float64(r.Dt))
+	}
+	return findGaps(append(dts, float64(to)))
+}
+
+// markGaps restituisce una copia dei record con GapBefore impostato sui record preceduti da un buco,
+// così che chi usa l'API possa interrompere le linee negli stessi punti dei grafici.
+func markGaps(records []Record) []Record {
+	marked := slices.Clone(records)
+	for i := 1; i < len(marked); i++ {
+		marked[i].GapBefore = isGap(float64(marked[i].Dt - marked[i-1].Dt))
+	}
+	return marked
+}
+
+func getGaps(f, t *int64) ([]Gap, error) {
+	records, err := getAllRecords(*f, *t)
+	if err != nil {
+		return nil, errors.New("errore nella lettura dei dati: " + err.Error())
+	}
+	return recordGaps(records, *f, *t), nil
+}
+
+// splitGaps divide una serie temporale in segmenti continui.
+func splitGaps(pts plotter.XYs) (segments []plotter.XYs) {
+	start := 0
+	for i := 1; i < len(pts); i++ {
+		if isGap(pts[i].X - pts[i-1].X) {
+			segments = append(segments, pts[start:i])
+			start = i
+		}
+	}
+	if start < len(pts) {
+		segments = append(segments, pts[start:])
+	}
+	return
+}
+
+// addTimeLines aggiunge una serie temporale senza unire i punti separati da un buco nei dati.
+// Ogni segmento viene sottocampionato in proporzione alla sua lunghezza, per un totale di circa points punti.
+func addTimeLines(p *plot.Plot, pts plotter.XYs, points int, color color.Color, dashed bool, label string) error {
+	segments := splitGaps(pts)
+	if len(segments) <= 1 {
+		return addLines(p, downsample(pts, points), color, dashed, label)
+	}
+
+	for i, s := range segments {
+		n := 0
+		if points > 0 {
+			n = max(points*len(s)/len(pts), 1)
+		}
+
+		l, err := newLine(downsample(s, n), color, dashed)
+		if err != nil {
+			return err
+		}
+		p.Add(l)
+		if i == 0 {
+			p.Legend.Add(label, l)
+		}
+	}
+	return nil
+}
+
+// gapBands implementa plot.Plotter tratteggiando gli intervalli senza dati.
+type gapBands struct {
+	gaps  []Gap
+	color color.Color
+}
+
+func (g *gapBands) Plot(c draw.Canvas, plt *plot.Plot) {
+	trX, _ := plt.Transforms(&c)
+	line := draw.LineStyle{Color: g.color, Width: vg.Points(0.5)}
+	height := c.Max.Y - c.Min.Y
+
+	for _, gap := range g.gaps {
+		x0, x1 := trX(float64(gap.From)), trX(float64(gap.To))
+		if x1 <= x0 {
+			continue
+		}
+
+		band := draw.Canvas{Canvas: c.Canvas, Rectangle: vg.Rectangle{
+			Min: vg.Point{X: max(x0, c.Min.X), Y: c.Min.Y},
+			Max: vg.Point{X: min(x1, c.Max.X), Y: c.Max.Y},
+		}}
+		for x := x0 - height; x < x1; x += vg.Points(hatchSpace) {
+			band.StrokeLines(line, band.ClipLinesXY([]vg.Point{{X: x, Y: c.Min.Y}, {X: x + height, Y: c.Max.Y}})...)
+		}
+	}
+}
+
+// Thumbnail disegna il tratteggio nella legenda.
+func (g *gapBands) Thumbnail(c *draw.Canvas) {
+	line := draw.LineStyle{Color: g.color, Width: vg.Points(0.5)}
+	h := c.Max.Y - c.Min.Y
+	for x := c.Min.X - h; x < c.Max.X; x += vg.Points(hatchSpace / 2) {
+		c.StrokeLines(line, c.ClipLinesXY([]vg.Point{{X: x, Y: c.Min.Y}, {X: x + h, Y: c.Max.Y}})...)
+	}
+}
@@ -0,0 +1,47 @@
+package src
+
+import (
+	"testing"
+
+	"gonum.org/v1/plot/plotter"
+)
+
+func TestSplitGaps(t *testing.T) {
+	cronInterval = 1800
+
+	pts := plotter.XYs{{X: 0}, {X: 1800}, {X: 3600}, {X: 3600 + 4*1800}, {X: 3600 + 5*1800}, {X: 100000}}
+	segments := splitGaps(pts)
+	if len(segments) != 3 {
+		t.Fatalf("got %d segments, want 3", len(segments))
+	}
+	for i, want := range []int{3, 2, 1} {
+		if len(segments[i]) != want {
+			t.Errorf("segment %d has %d points, want %d", i, len(segments[i]), want)
+		}
+	}
+
+	gaps := recordGaps([]Record{{Dt: 1800}, {Dt: 3600}, {Dt: 30000}}, 0, 30000)
+	if len(gaps) != 1 || gaps[0] != (Gap{From: 3600, To: 30000}) {
+		t.Errorf("got gaps %v, want [{3600 30000}]", gaps)
+	}
+
+	if gaps := recordGaps(nil, 0, 86400); len(gaps) != 1 {
+		t.Errorf("an empty range should be a single gap, got %v", gaps)
+	}
+}
+
+func TestMarkGaps(t *testing.T) {
+	cronInterval = 1800
+
+	records := []Record{{Dt: 0}, {Dt: 1800}, {Dt: 1800 + 4*1800}, {Dt: 1800 + 5*1800}}
+	marked := markGaps(records)
+	want := []bool{false, false, true, false}
+	for i := range want {
+		if marked[i].GapBefore != want[i] {
+			t.Errorf("record %d: got gap_before %v, want %v", i, marked[i].GapBefore, want[i])
+		}
+	}
+	if records[2].GapBefore {
+		t.Error("markGaps modified the cached records")
+	}
+}
@@ -0,0 +1,198 @@
+package src
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"math"
+	"time"
+
+	bh "github.com/birabittoh/bunnyhue"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+const (
+	heatmapHeight = 2 * vg.Inch
+	heatmapSteps  = 5
+	heatmapLegend = 8 // colonne lasciate libere a destra per la legenda
+)
+
+var weekdays = []string{"Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"}
+
+// DailyValue è il valore aggregato di una misura in un giorno.
+type DailyValue struct {
+	Day   time.Time
+	Value float64
+}
+
+// getDailyValues aggrega una misura per giorno: le precipitazioni vengono sommate, le altre misure mediate.
+func getDailyValues(measure string, f, t *int64, us UnitSystem) (dv []DailyValue, err error) {
+	if measure == "rain_1h" || measure == "snow_1h" {
+		var p Precipitation
+		p, err = getPrecipitation(f, t, periodDay)
+		if err != nil {
+			return
+		}
+		for _, total := range p.Totals {
+			v := total.Rain
+			if measure == "snow_1h" {
+				v = total.Snow
+			}
+			dv = append(dv, DailyValue{Day: localTime(total.Start), Value: us.Convert(measure, v)})
+		}
+		return
+	}
+
+	dp, err := us.dataPoints([]string{measure}, f, t)
+	if err != nil {
+		err = errors.New("errore nella lettura dei dati: " + err.Error())
+		return
+	}
+
+	var count float64
+	for _, p := range dp {
+		d := dayStart(localTime(int64(p.Dt)))
+		if len(dv) == 0 || !dv[len(dv)-1].Day.Equal(d) {
+			dv = append(dv, DailyValue{Day: d, Value: p.Value0})
+			count = 1
+			continue
+		}
+		last := &dv[len(dv)-1]
+		last.Value = (last.Value*count + p.Value0) / (count + 1)
+		count++
+	}
+	return
+}
+
+// colorScale interpola linearmente tra i colori di stops, con v compreso tra 0 e 1.
+func colorScale(stops []color.Color, v float64) color.Color {
+	v = min(max(v, 0), 1) * float64(len(stops)-1)
+	i := min(int(v), len(stops)-2)
+	frac := v - float64(i)
+
+	r0, g0, b0, _ := stops[i].RGBA()
+	r1, g1, b1, _ := stops[i+1].RGBA()
+	lerp := func(a, b uint32) uint8 {
+		return uint8((float64(a)*(1-frac) + float64(b)*frac) / 257)
+	}
+	return color.RGBA{R: lerp(r0, r1), G: lerp(g0, g1), B: lerp(b0, b1), A: 255}
+}
+
+// calendarHeatmap implementa plot.Plotter disegnando una cella per ogni giorno dell'anno,
+// con le settimane sull'asse X e i giorni della settimana sull'asse Y (lunedì in alto).
+type calendarHeatmap struct {
+	start    time.Time // lunedì della prima settimana
+	days     []DailyValue
+	min, max float64
+	stops    []color.Color
+	empty    color.Color
+	year     int
+}
+
+// cell restituisce la colonna (settimana) e la riga (giorno della settimana) di un giorno.
+// I giorni trascorsi da start si contano sulle date di calendario, perché con l'ora legale
+// un giorno può durare 23 o 25 ore.
+func (h *calendarHeatmap) cell(d time.Time) (x, y float64) {
+	y0, m0, d0 := h.start.Date()
+	y1, m1, d1 := d.Date()
+	days := time.Date(y1, m1, d1, 0, 0, 0, 0, time.UTC).Sub(time.Date(y0, m0, d0, 0, 0, 0, 0, time.UTC)).Hours() / 24
+	return math.Floor(days / 7), float64(6 - (int(d.Weekday())+6)%7)
+}
+
+func (h *calendarHeatmap) value(v float64) float64 {
+	if h.max == h.min {
+		return 0.5
+	}
+	return (v - h.min) / (h.max - h.min)
+}
+
+func (h *calendarHeatmap) Plot(c draw.Canvas, plt *plot.Plot) {
+	trX, trY := plt.Transforms(&c)
+	gap := vg.Points(1)
+
+	fill := func(x, y float64, clr color.Color) {
+		x0, x1 := trX(x-0.5)+gap/2, trX(x+0.5)-gap/2
+		y0, y1 := trY(y-0.5)+gap/2, trY(y+0.5)-gap/2
+		c.FillPolygon(clr, c.ClipPolygonXY([]vg.Point{{X: x0, Y: y0}, {X: x0, Y: y1}, {X: x1, Y: y1}, {X: x1, Y: y0}}))
+	}
+
+	for d := time.Date(h.year, 1, 1, 0, 0, 0, 0, appLocation); d.Year() == h.year; d = d.AddDate(0, 0, 1) {
+		x, y := h.cell(d)
+		fill(x, y, h.empty)
+	}
+	for _, dv := range h.days {
+		x, y := h.cell(dv.Day)
+		fill(x, y, colorScale(h.stops, h.value(dv.Value)))
+	}
+}
+
+func (h *calendarHeatmap) DataRange() (xmin, xmax, ymin, ymax float64) {
+	last, _ := h.cell(time.Date(h.year, 12, 31, 0, 0, 0, 0, appLocation))
+	return -0.5, last + 0.5 + heatmapLegend, -0.5, 6.5
+}
+
+func plotHeatmap(measure string, year int, us UnitSystem, l *Locale, palette *bh.Palette) (p *plot.Plot, err error) {
+	from := time.Date(year, 1, 1, 0, 0, 0, 0, appLocation)
+	to := from.AddDate(1, 0, 0)
+	f, t := alignConstraints(from.Unix(), to.Unix()-1)
+
+	dv, err := getDailyValues(measure, f, t, us)
+	if err != nil {
+		return
+	}
+
+	h := &calendarHeatmap{
+		start: from.AddDate(0, 0, -(int(from.Weekday())+6)%7),
+		days:  dv,
+		stops: []color.Color{palette.Blue, palette.Green, palette.Orange, palette.Red},
+		empty: palette.Contrast,
+		year:  year,
+	}
+	if measure == "rain_1h" || measure == "snow_1h" {
+		h.stops = []color.Color{palette.Contrast, palette.Cyan, palette.Blue}
+	}
+	if len(dv) > 0 {
+		h.min, h.max = dv[0].Value, dv[0].Value
+		for _, d := range dv {
+			h.min = min(h.min, d.Value)
+			h.max = max(h.max, d.Value)
+		}
+	}
+
+	p = newPlot(l, palette)
+	p.Add(h)
+	p.X.Tick.Label.Rotation = 0
+	p.X.Tick.Label.XAlign = draw.XCenter
+	p.X.Tick.Label.YAlign = draw.YTop
+	p.Y.Tick.Length = 0
+	p.X.Tick.Length = 0
+	p.X.LineStyle.Width = 0
+	p.Y.LineStyle.Width = 0
+
+	// Un tick per mese sull'asse X e uno per giorno della settimana sull'asse Y
+	var months []plot.Tick
+	for m := from; m.Before(to); m = m.AddDate(0, 1, 0) {
+		x, _ := h.cell(m)
+		months = append(months, plot.Tick{Value: x, Label: l.formatTime(m, "Jan")})
+	}
+	p.X.Tick.Marker = plot.ConstantTicks(months)
+
+	var days []plot.Tick
+	for i, d := range weekdays {
+		days = append(days, plot.Tick{Value: float64(6 - i), Label: l.T(d)})
+	}
+	p.Y.Tick.Marker = plot.ConstantTicks(days)
+
+	// Legenda con la scala dei colori
+	p.Legend.Top = true
+	for i := 0; i < heatmapSteps && len(dv) > 0; i++ {
+		v := h.min + (h.max-h.min)*float64(i)/(heatmapSteps-1)
+		p.Legend.Add(us.Info(measure).Format(v), legendThumb{colorScale(h.stops, h.value(v))})
+	}
+
+	p.Title.Text = fmt.Sprintf("%s %d", l.Label(us, measure), year)
+	p.Title.TextStyle.Font = plotFont
+	return
+}
@@ -0,0 +1,29 @@
+package src
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCalendarHeatmapCellDST(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Rome")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Il 30 marzo 2025 dura 23 ore: il lunedì successivo deve iniziare una nuova colonna
+	h := &calendarHeatmap{start: time.Date(2024, 12, 30, 0, 0, 0, 0, loc), year: 2025}
+	sunX, sunY := h.cell(time.Date(2025, 3, 30, 0, 0, 0, 0, loc))
+	monX, monY := h.cell(time.Date(2025, 3, 31, 0, 0, 0, 0, loc))
+	if sunY != 0 || monY != 6 {
+		t.Errorf("got rows %v and %v, want 0 and 6", sunY, monY)
+	}
+	if sunX != 12 || monX != 13 {
+		t.Errorf("got weeks %v and %v, want 12 and 13", sunX, monX)
+	}
+
+	// Il 26 ottobre 2025 dura 25 ore: il lunedì successivo apre comunque una nuova settimana
+	if x, _ := h.cell(time.Date(2025, 10, 27, 0, 0, 0, 0, loc)); x != 43 {
+		t.Errorf("got week %v for 27 October, want 43", x)
+	}
+}
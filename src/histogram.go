@@ -0,0 +1,117 @@
+package src
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	bh "github.com/birabittoh/bunnyhue"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+const (
+	defaultBins = 20
+	maxBins     = 200
+)
+
+// Histogram descrive la distribuzione dei valori di una misura.
+type Histogram struct {
+	Measure string    `json:"measure"`
+	Edges   []float64 `json:"edges"`
+	Counts  []int     `json:"counts"`
+	Mean    float64   `json:"mean"`
+	Median  float64   `json:"median"`
+}
+
+// computeHistogram divide i valori in bins intervalli della stessa ampiezza tra minimo e massimo.
+func computeHistogram(values []float64, bins int) (h Histogram) {
+	h.Edges = []float64{}
+	h.Counts = []int{}
+	if len(values) == 0 {
+		return
+	}
+
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	lo, hi := sorted[0], sorted[len(sorted)-1]
+	if lo == hi {
+		lo, hi = lo-0.5, hi+0.5
+	}
+
+	width := (hi - lo) / float64(bins)
+	h.Edges = make([]float64, bins+1)
+	for i := range h.Edges {
+		h.Edges[i] = lo + width*float64(i)
+	}
+	h.Edges[bins] = hi
+
+	h.Counts = make([]int, bins)
+	var sum float64
+	for _, v := range sorted {
+		i := min(int((v-lo)/width), bins-1)
+		h.Counts[i]++
+		sum += v
+	}
+
+	h.Mean = sum / float64(len(sorted))
+	h.Median = quantile(sorted, 0.5)
+	return
+}
+
+func getHistogram(measure string, bins int, f, t *int64, us UnitSystem) (h Histogram, err error) {
+	if bins < 1 || bins > maxBins {
+		err = fmt.Errorf("il numero di intervalli deve essere compreso tra 1 e %d", maxBins)
+		return
+	}
+
+	dp, err := us.dataPoints([]string{measure}, f, t)
+	if err != nil {
+		err = errors.New("errore nella lettura dei dati: " + err.Error())
+		return
+	}
+
+	values := make([]float64, len(dp))
+	for i := range dp {
+		values[i] = dp[i].Value0
+	}
+
+	h = computeHistogram(values, bins)
+	h.Measure = measure
+	return
+}
+
+func plotHistogram(measure string, bins int, f, t *int64, us UnitSystem, l *Locale, palette *bh.Palette) (p *plot.Plot, err error) {
+	h, err := getHistogram(measure, bins, f, t, us)
+	if err != nil {
+		return
+	}
+
+	p = newPlot(l, palette)
+	resetValueAxis(&p.X)
+	setAxisLabel(&p.X, l.Label(us, measure))
+	if len(h.Counts) == 0 {
+		return
+	}
+
+	hist := &plotter.Histogram{
+		Width:     h.Edges[1] - h.Edges[0],
+		FillColor: palette.Blue,
+		LineStyle: draw.LineStyle{Color: palette.Background, Width: vg.Points(0.5)},
+	}
+	var top float64
+	for i, c := range h.Counts {
+		hist.Bins = append(hist.Bins, plotter.HistogramBin{Min: h.Edges[i], Max: h.Edges[i+1], Weight: float64(c)})
+		top = max(top, float64(c))
+	}
+	p.Add(hist)
+
+	err = addLines(p, plotter.XYs{{X: h.Mean, Y: 0}, {X: h.Mean, Y: top}}, palette.Orange, false, l.T("Mean %s", l.Float(h.Mean, 2)))
+	if err != nil {
+		return
+	}
+	err = addLines(p, plotter.XYs{{X: h.Median, Y: 0}, {X: h.Median, Y: top}}, palette.Primary, true, l.T("Median %s", l.Float(h.Median, 2)))
+	return
+}
@@ -0,0 +1,280 @@
+package src
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"maps"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultLang = "en"
+	langCookie  = "lang"
+	localesDir  = "locales"
+)
+
+// Locale raccoglie i testi e le convenzioni di formattazione di una lingua.
+// I messaggi sono indicizzati con il testo inglese, che viene usato quando manca una traduzione.
+type Locale struct {
+	Lang       string            `json:"-"`
+	Name       string            `json:"name"`        // nome della lingua, nella lingua stessa
+	Decimal    string            `json:"decimal"`     // separatore decimale
+	DateFormat string            `json:"date_format"` // layout di time.Format
+	TimeFormat string            `json:"time_format"`
+	Messages   map[string]string `json:"messages"`
+
+	conditions map[string]Condition
+}
+
+var (
+	locales     map[string]*Locale
+	localesOnce sync.Once
+	localesErr  error
+)
+
+func newDefaultLocale() *Locale {
+	return &Locale{Lang: defaultLang, Name: "English", Decimal: ".", DateFormat: "2006-01-02", TimeFormat: "15:04"}
+}
+
+// loadLocales legge i cataloghi locales/<lingua>.json e le condizioni tradotte conditions.<lingua>.json.
+// L'inglese è sempre disponibile; le condizioni mancanti in una traduzione restano in inglese.
+func loadLocales() (map[string]*Locale, error) {
+	localesOnce.Do(func() {
+		def, err := loadConditions()
+		if err != nil {
+			localesErr = err
+			return
+		}
+
+		en := newDefaultLocale()
+		en.conditions = def
+		locales = map[string]*Locale{defaultLang: en}
+
+		files, err := fs.Glob(getAssets(), localesDir+"/*.json")
+		if err != nil {
+			localesErr = err
+			return
+		}
+		for _, file := range files {
+			lang := strings.TrimSuffix(path.Base(file), ".json")
+			l, err := loadLocale(file, lang, def)
+			if err != nil {
+				localesErr = errors.New("errore nel caricamento della lingua " + lang + ": " + err.Error())
+				return
+			}
+			locales[lang] = l
+		}
+	})
+	return locales, localesErr
+}
+
+func loadLocale(file, lang string, def map[string]Condition) (*Locale, error) {
+	b, err := fs.ReadFile(getAssets(), file)
+	if err != nil {
+		return nil, err
+	}
+
+	l := newDefaultLocale()
+	if err = json.Unmarshal(b, l); err != nil {
+		return nil, err
+	}
+	l.Lang = lang
+
+	l.conditions = maps.Clone(def)
+	b, err = fs.ReadFile(getAssets(), "conditions."+lang+".json")
+	if errors.Is(err, fs.ErrNotExist) {
+		log.Printf("Condizioni non tradotte per la lingua %s\n", lang)
+		return l, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var translated map[string]Condition
+	if err = json.Unmarshal(b, &translated); err != nil {
+		return nil, err
+	}
+	for id, c := range translated {
+		if c.Icon == "" {
+			c.Icon = def[id].Icon
+		}
+		l.conditions[id] = c
+	}
+	return l, nil
+}
+
+// languages restituisce le lingue disponibili, ordinate per codice.
+func languages() []*Locale {
+	ls := slices.Collect(maps.Values(locales))
+	slices.SortFunc(ls, func(a, b *Locale) int { return strings.Compare(a.Lang, b.Lang) })
+	return ls
+}
+
+// negotiateLocale sceglie la lingua in base all'header Accept-Language, confrontando
+// anche la sola lingua principale (es. "it" per "it-CH").
+func negotiateLocale(accept string) (*Locale, bool) {
+	for _, tag := range acceptValues(accept) {
+		lang, _, _ := strings.Cut(tag, "-")
+		if l, ok := locales[lang]; ok {
+			return l, true
+		}
+	}
+	return nil, false
+}
+
+// getLocale legge la lingua dal parametro lang, dal cookie impostato dalle pagine
+// o dall'header Accept-Language. La lingua predefinita è l'inglese.
+func getLocale(r *http.Request) (*Locale, error) {
+	if lang := r.URL.Query().Get("lang"); lang != "" {
+		l, ok := locales[lang]
+		if !ok {
+			return nil, errors.New("lingua non supportata: " + lang)
+		}
+		return l, nil
+	}
+
+	if c, err := r.Cookie(langCookie); err == nil {
+		if l, ok := locales[c.Value]; ok {
+			return l, nil
+		}
+	}
+	if l, ok := negotiateLocale(r.Header.Get("Accept-Language")); ok {
+		return l, nil
+	}
+	return locales[defaultLang], nil
+}
+
+// setLangCookie salva la lingua scelta esplicitamente, come setUnitsCookie.
+func setLangCookie(w http.ResponseWriter, r *http.Request, l *Locale) {
+	if r.URL.Query().Get("lang") == "" {
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     langCookie,
+		Value:    l.Lang,
+		Path:     "/",
+		Expires:  time.Now().AddDate(1, 0, 0),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// T traduce un messaggio; con degli argomenti il messaggio tradotto è usato come formato di fmt.Sprintf.
+func (l *Locale) T(msg string, args ...any) string {
+	if t, ok := l.Messages[msg]; ok {
+		msg = t
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// Float formatta un numero con precision cifre decimali e il separatore della lingua.
+func (l *Locale) Float(v float64, precision int) string {
+	return strings.Replace(strconv.FormatFloat(v, 'f', precision, 64), ".", l.Decimal, 1)
+}
+
+// formatTime formatta t come time.Format, traducendo le abbreviazioni dei giorni ("Mon") e dei mesi ("Jan").
+func (l *Locale) formatTime(t time.Time, layout string) string {
+	s := t.Format(layout)
+	for _, name := range []string{"Mon", "Jan"} {
+		if strings.Contains(layout, name) {
+			s = strings.Replace(s, t.Format(name), l.T(t.Format(name)), 1)
+		}
+	}
+	return s
+}
+
+func (l *Locale) Date(ts int64) string {
+	return localTime(ts).Format(l.DateFormat)
+}
+
+func (l *Locale) DateTime(ts int64) string {
+	return localTime(ts).Format(l.DateFormat + " " + l.TimeFormat)
+}
+
+func (l *Locale) Time(ts int64) string {
+	return localTime(ts).Format(l.TimeFormat)
+}
+
+// Duration formatta una durata in ore, minuti e secondi con le abbreviazioni della lingua,
+// omettendo le unità nulle.
+func (l *Locale) Duration(d time.Duration) string {
+	d = d.Round(time.Second)
+	h, m, s := int(d/time.Hour), int(d%time.Hour/time.Minute), int(d%time.Minute/time.Second)
+
+	var parts []string
+	if h > 0 {
+		parts = append(parts, l.T("%dh", h))
+	}
+	if m > 0 {
+		parts = append(parts, l.T("%dm", m))
+	}
+	if s > 0 || len(parts) == 0 {
+		parts = append(parts, l.T("%ds", s))
+	}
+	return strings.Join(parts, " ")
+}
+
+// Since formatta il tempo trascorso da un timestamp Unix.
+func (l *Locale) Since(ts int64) string {
+	return l.Duration(time.Since(time.Unix(ts, 0)))
+}
+
+// WindDirection restituisce la freccia e il punto cardinale tradotto di una direzione del vento.
+func (l *Locale) WindDirection(deg float64) string {
+	if deg < 0 {
+		return ""
+	}
+	return getWindDirection(deg) + " " + l.T(compass[windSector(deg)])
+}
+
+// Info restituisce i metadati di una misura nelle unità del sistema, con nome e descrizione tradotti.
+func (l *Locale) Info(us UnitSystem, measure string) MeasureInfo {
+	info := us.Info(measure)
+	info.Name = l.T(info.Name)
+	info.Description = l.T(info.Description)
+	return info
+}
+
+func (l *Locale) Label(us UnitSystem, measure string) string {
+	return l.Info(us, measure).Label()
+}
+
+func (l *Locale) MeasureName(measure string) string {
+	return l.T(measureName(measure))
+}
+
+// Value converte un valore canonico nelle unità del sistema e lo formatta senza unità.
+// Accetta anche valori interi, per l'uso nei template.
+func (l *Locale) Value(us UnitSystem, measure string, v any) string {
+	return l.Float(us.Convert(measure, toFloat(v)), us.Info(measure).Precision)
+}
+
+// Format è come Value, ma aggiunge l'unità del sistema.
+func (l *Locale) Format(us UnitSystem, measure string, v any) string {
+	return l.Value(us, measure, v) + us.Unit(measure)
+}
+
+// localize restituisce una copia dei record con le condizioni nella lingua scelta,
+// senza modificare quelli in cache.
+func (l *Locale) localize(records []Record) []Record {
+	if l.Lang == defaultLang {
+		return records
+	}
+
+	localized := slices.Clone(records)
+	for i := range localized {
+		localized[i].Conditions = localized[i].getConditions(l.conditions)
+	}
+	return localized
+}
@@ -0,0 +1,124 @@
+package src
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func testLocales() {
+	it := newDefaultLocale()
+	it.Lang, it.Name, it.Decimal = "it", "Italiano", ","
+	it.Messages = map[string]string{"Temperature": "Temperatura", "Last updated %s ago": "Aggiornato %s fa", "%dh": "%d h", "%dm": "%d min", "W": "O", "Wed": "mer", "Jan": "gen"}
+	it.conditions = map[string]Condition{"800": {Name: "Sereno", Description: "cielo sereno", Icon: "01"}}
+
+	en := newDefaultLocale()
+	en.conditions = map[string]Condition{"800": {Name: "Clear", Description: "clear sky", Icon: "01"}}
+	locales = map[string]*Locale{"en": en, "it": it}
+}
+
+func TestGetLocale(t *testing.T) {
+	testLocales()
+	tests := []struct {
+		query  string
+		cookie string
+		accept string
+		want   string
+		err    bool
+	}{
+		{"", "", "", "en", false},
+		{"lang=it", "", "", "it", false},
+		{"", "it", "", "it", false},
+		{"", "", "it-CH, en;q=0.8", "it", false},
+		{"", "", "de-DE, it;q=0.5, en;q=0.7", "en", false},
+		{"", "", "de-DE, fr;q=0.5", "en", false},
+		{"", "", "it;q=0, en", "en", false},
+		{"lang=en", "it", "it", "en", false},
+		{"", "xx", "it", "it", false},
+		{"lang=xx", "", "", "", true},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/?"+tt.query, nil)
+		if tt.cookie != "" {
+			r.Header.Set("Cookie", langCookie+"="+tt.cookie)
+		}
+		if tt.accept != "" {
+			r.Header.Set("Accept-Language", tt.accept)
+		}
+
+		l, err := getLocale(r)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%q: expected an error, got %s", tt.query, l.Lang)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.query, err)
+			continue
+		}
+		if l.Lang != tt.want {
+			t.Errorf("%q (cookie %q, Accept-Language %q): got %s, want %s", tt.query, tt.cookie, tt.accept, l.Lang, tt.want)
+		}
+	}
+}
+
+func TestLocaleFormatting(t *testing.T) {
+	testLocales()
+	it, en := locales["it"], locales["en"]
+
+	if got := it.T("Last updated %s ago", "5m0s"); got != "Aggiornato 5m0s fa" {
+		t.Errorf("T: got %q", got)
+	}
+	if got := it.T("Wind rose"); got != "Wind rose" {
+		t.Errorf("missing translation: got %q", got)
+	}
+	if got := it.Label(unitSystems["imperial"], "temp"); got != "Temperatura (°F)" {
+		t.Errorf("Label: got %q", got)
+	}
+	if got := it.Format(unitSystems["metric"], "temp", 21.46); got != "21,5°C" {
+		t.Errorf("Format: got %q", got)
+	}
+	if got := en.Value(unitSystems["metric"], "visibility", 10000); got != "10000" {
+		t.Errorf("Value: got %q", got)
+	}
+	if got := it.Duration(time.Hour + 5*time.Minute); got != "1 h 5 min" {
+		t.Errorf("Duration: got %q", got)
+	}
+	if got := en.Duration(300 * time.Millisecond); got != "0s" {
+		t.Errorf("Duration: got %q", got)
+	}
+	if got := it.WindDirection(260); got != "← O" {
+		t.Errorf("WindDirection: got %q", got)
+	}
+	if got := en.WindDirection(-1); got != "" {
+		t.Errorf("WindDirection: got %q", got)
+	}
+
+	day := time.Date(2025, 1, 15, 9, 0, 0, 0, time.UTC)
+	if got := it.formatTime(day, "Mon 02 Jan 2006"); got != "mer 15 gen 2025" {
+		t.Errorf("formatTime: got %q", got)
+	}
+	if got := en.formatTime(day, "Mon 02 Jan"); got != "Wed 15 Jan" {
+		t.Errorf("formatTime: got %q", got)
+	}
+	ticks := timeTicks{loc: time.UTC, l: it}.Ticks(float64(day.Unix()), float64(day.AddDate(0, 2, 0).Unix()))
+	if len(ticks) == 0 || ticks[0].Label != "20 gen" {
+		t.Errorf("timeTicks: got %v", ticks)
+	}
+}
+
+func TestLocalize(t *testing.T) {
+	testLocales()
+	records := []Record{{Dt: 1000, Sunrise: 500, Sunset: 2000, Weather: "800"}}
+	records[0].Conditions = records[0].getConditions(locales["en"].conditions)
+
+	localized := locales["it"].localize(records)
+	if got := localized[0].Conditions[0]; got.Description != "cielo sereno" || got.Icon != "01d" {
+		t.Errorf("localized condition: got %+v", got)
+	}
+	if got := records[0].Conditions[0].Description; got != "clear sky" {
+		t.Errorf("the cached record was modified: got %q", got)
+	}
+}
@@ -0,0 +1,88 @@
+package src
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	iconsMaxAge     = 365 * 24 * time.Hour
+	iconsShortAge   = 24 * time.Hour // per le richieste senza la versione corrente
+	iconsDefaultURL = "/static/icons/{icon}.svg"
+)
+
+var (
+	iconsVersion     string
+	iconsVersionOnce sync.Once
+)
+
+// iconURL restituisce l'URL dell'icona di una condizione (es. "10d"), secondo il modello in ICONS_URL.
+// Per esempio, con //openweathermap.org/img/wn/{icon}@4x.png si usano di nuovo le icone di OpenWeatherMap.
+// Le icone servite dall'applicazione hanno la versione nel parametro v, così da poterle tenere in cache a lungo.
+func iconURL(icon string) string {
+	pattern, ok := os.LookupEnv("ICONS_URL")
+	if !ok {
+		pattern = iconsDefaultURL + "?v=" + getIconsVersion()
+	}
+	return strings.ReplaceAll(pattern, "{icon}", icon)
+}
+
+// getIconsFS restituisce le icone da servire: una per codice di OpenWeatherMap, con le varianti
+// diurna (d) e notturna (n), prese dalla cartella ICONS_DIR o dalle risorse dell'applicazione.
+func getIconsFS() fs.FS {
+	if dir := os.Getenv("ICONS_DIR"); dir != "" {
+		return os.DirFS(dir)
+	}
+	icons, _ := fs.Sub(getAssets(), "static/icons")
+	return icons
+}
+
+// getIconsVersion restituisce un'impronta dei nomi e del contenuto delle icone, calcolata al primo uso:
+// sostituendo le icone e riavviando l'applicazione cambiano anche gli URL.
+func getIconsVersion() string {
+	iconsVersionOnce.Do(func() {
+		icons := getIconsFS()
+		h := sha256.New()
+		fs.WalkDir(icons, ".", func(name string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			b, err := fs.ReadFile(icons, name)
+			if err != nil {
+				return err
+			}
+			h.Write([]byte(name + "\x00"))
+			h.Write(b)
+			return nil
+		})
+		iconsVersion = hex.EncodeToString(h.Sum(nil))[:12]
+	})
+	return iconsVersion
+}
+
+// getIconsHandler serve le icone con una cache lunga quando l'URL contiene la versione corrente,
+// e con una più breve altrimenti.
+func getIconsHandler() http.HandlerFunc {
+	icons := getIconsFS()
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("icon")
+		if info, err := fs.Stat(icons, name); err != nil || info.IsDir() {
+			http.NotFound(w, r)
+			return
+		}
+
+		if r.URL.Query().Get("v") == getIconsVersion() {
+			w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(int(iconsMaxAge.Seconds()))+", immutable")
+		} else {
+			w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(int(iconsShortAge.Seconds())))
+		}
+		http.ServeFileFS(w, r, icons, name)
+	}
+}
@@ -0,0 +1,78 @@
+package src
+
+import (
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestIconsCoverConditions(t *testing.T) {
+	b, err := fs.ReadFile(embeddedAssets, "conditions.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var table map[string]Condition
+	if err = json.Unmarshal(b, &table); err != nil {
+		t.Fatal(err)
+	}
+
+	for id, c := range table {
+		for _, variant := range []string{"d", "n"} {
+			name := "static/icons/" + c.Icon + variant + ".svg"
+			if _, err := fs.Stat(embeddedAssets, name); err != nil {
+				t.Errorf("condition %s: missing icon %s", id, name)
+			}
+		}
+	}
+}
+
+func TestIconsHandler(t *testing.T) {
+	s := http.NewServeMux()
+	s.HandleFunc("GET /static/icons/{icon}", getIconsHandler())
+
+	tests := []struct {
+		path      string
+		status    int
+		cached    bool
+		immutable bool
+	}{
+		{"/static/icons/10d.svg?v=" + getIconsVersion(), http.StatusOK, true, true},
+		{"/static/icons/10d.svg", http.StatusOK, true, false},
+		{"/static/icons/10d.svg?v=old", http.StatusOK, true, false},
+		{"/static/icons/10x.svg", http.StatusNotFound, false, false},
+		{"/static/icons/", http.StatusNotFound, false, false},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != tt.status {
+			t.Errorf("%s: status %d, want %d", tt.path, w.Code, tt.status)
+		}
+		cc := w.Header().Get("Cache-Control")
+		if cached := cc != ""; cached != tt.cached {
+			t.Errorf("%s: Cache-Control %q", tt.path, cc)
+		}
+		if immutable := strings.Contains(cc, "immutable"); immutable != tt.immutable {
+			t.Errorf("%s: Cache-Control %q", tt.path, cc)
+		}
+		if tt.status == http.StatusOK && w.Header().Get("Content-Type") != "image/svg+xml" {
+			t.Errorf("%s: Content-Type %q", tt.path, w.Header().Get("Content-Type"))
+		}
+	}
+}
+
+func TestIconURL(t *testing.T) {
+	if v := getIconsVersion(); len(v) != 12 {
+		t.Errorf("unexpected icons version %q", v)
+	}
+	if got := iconURL("10d"); got != "/static/icons/10d.svg?v="+getIconsVersion() {
+		t.Errorf("iconURL = %q", got)
+	}
+	t.Setenv("ICONS_URL", "//openweathermap.org/img/wn/{icon}@4x.png")
+	if got := iconURL("10d"); got != "//openweathermap.org/img/wn/10d@4x.png" {
+		t.Errorf("iconURL = %q", got)
+	}
+}
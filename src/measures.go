@@ -0,0 +1,63 @@
+package src
+
+import "strconv"
+
+// MeasureInfo descrive come mostrare una misura: nome, unità, cifre decimali e descrizione.
+type MeasureInfo struct {
+	Name        string `json:"name"`
+	Unit        string `json:"unit"`
+	Precision   int    `json:"precision"`
+	Description string `json:"description"`
+	Quantity    string `json:"quantity,omitempty"` // grandezza fisica, per la conversione delle unità
+}
+
+// measureInfo è indicizzata con i nomi delle colonne di measures.
+var measureInfo = map[string]MeasureInfo{
+	"visibility":        {"Visibility", "m", 0, "Horizontal visibility", quantityDistance},
+	"sunrise":           {"Sunrise", "", 0, "Time of sunrise (Unix timestamp)", ""},
+	"sunset":            {"Sunset", "", 0, "Time of sunset (Unix timestamp)", ""},
+	"temp":              {"Temperature", "°C", 1, "Air temperature", quantityTemperature},
+	"temp_min":          {"Min temperature", "°C", 1, "Minimum temperature currently observed in the area", quantityTemperature},
+	"temp_max":          {"Max temperature", "°C", 1, "Maximum temperature currently observed in the area", quantityTemperature},
+	"feels_like":        {"Feels like", "°C", 1, "Perceived temperature, as reported by OpenWeatherMap", quantityTemperature},
+	"pressure":          {"Pressure", "hPa", 0, "Atmospheric pressure", quantityPressure},
+	"sea_level":         {"Sea level pressure", "hPa", 0, "Atmospheric pressure at sea level", quantityPressure},
+	"grnd_level":        {"Ground level pressure", "hPa", 0, "Atmospheric pressure at ground level", quantityPressure},
+	"humidity":          {"Humidity", "%", 0, "Relative humidity", ""},
+	"wind_speed":        {"Wind speed", "m/s", 1, "Wind speed", quantitySpeed},
+	"wind_deg":          {"Wind direction", "°", 0, "Direction the wind blows from", ""},
+	"clouds":            {"Clouds", "%", 0, "Cloud cover", ""},
+	"rain_1h":           {"Rain", "mm/h", 2, "Rain volume in the last hour", quantityRate},
+	"snow_1h":           {"Snow", "mm/h", 2, "Snow volume in the last hour", quantityRate},
+	"dew_point":         {"Dew point", "°C", 1, "Temperature at which the air becomes saturated", quantityTemperature},
+	"absolute_humidity": {"Absolute humidity", "g/m³", 1, "Mass of water vapour per volume of air", ""},
+	"heat_index":        {"Heat index", "°C", 1, "Perceived temperature in hot and humid weather", quantityTemperature},
+	"wind_chill":        {"Wind chill", "°C", 1, "Perceived temperature in cold and windy weather", quantityTemperature},
+	"humidex":           {"Humidex", "°C", 1, "Canadian index of perceived temperature in hot weather", quantityTemperature},
+	"apparent_temp":     {"Apparent temperature", "°C", 1, "Perceived temperature taking humidity and wind into account", quantityTemperature},
+}
+
+// getMeasureInfo restituisce i metadati di una misura, con dei valori predefiniti per quelle non registrate.
+func getMeasureInfo(measure string) MeasureInfo {
+	if info, ok := measureInfo[measure]; ok {
+		return info
+	}
+	return MeasureInfo{Name: capitalize(measure), Precision: 2}
+}
+
+// Label restituisce il nome della misura seguito dall'unità, da usare come etichetta degli assi.
+func (m MeasureInfo) Label() string {
+	if m.Unit == "" {
+		return m.Name
+	}
+	return m.Name + " (" + m.Unit + ")"
+}
+
+// Format formatta un valore con la precisione e l'unità della misura.
+func (m MeasureInfo) Format(v float64) string {
+	return strconv.FormatFloat(v, 'f', m.Precision, 64) + m.Unit
+}
+
+func measureName(measure string) string {
+	return getMeasureInfo(measure).Name
+}
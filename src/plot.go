@@ -3,7 +3,10 @@ package src
 import (
 	"bytes"
 	"errors"
+	"io"
 	"math"
+	"strconv"
+	"strings"
 	"time"
 
 	"image/color"
@@ -12,6 +15,11 @@ import (
 	"gonum.org/v1/plot/font"
 	"gonum.org/v1/plot/plotter"
 	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+	"gonum.org/v1/plot/vg/vgeps"
+	"gonum.org/v1/plot/vg/vgimg"
+	"gonum.org/v1/plot/vg/vgpdf"
+	"gonum.org/v1/plot/vg/vgsvg"
 
 	bh "github.com/birabittoh/bunnyhue"
 	"github.com/hashicorp/golang-lru/v2/expirable"
@@ -22,6 +30,10 @@ const (
 	plotHeight = 4 * vg.Inch
 	fontFamily = "Arial, sans-serif"
 	tickFormat = "15:04 02/01"
+
+	maxOverlayYears = 5
+
+	week = 24 * 7 * time.Hour
 )
 
 var (
@@ -43,65 +55,98 @@ type DataPoint struct {
 	Value4 float64
 }
 
-// customTimeTicks implementa plot.Ticker
-type customTimeTicks struct {
-	times []time.Time
+func (d *DataPoint) setValue(i int, v float64) {
+	switch i {
+	case 0:
+		d.Value0 = v
+	case 1:
+		d.Value1 = v
+	case 2:
+		d.Value2 = v
+	case 3:
+		d.Value3 = v
+	case 4:
+		d.Value4 = v
+	}
 }
 
-func (ct customTimeTicks) Ticks(min, max float64) []plot.Tick {
-	// Prima raccogliamo tutti i timestamp validi (quelli compresi in [min, max])
-	var validTimes []time.Time
-	for _, t := range ct.times {
-		unix := float64(t.Unix())
-		if unix < min || unix > max {
-			continue
-		}
-		validTimes = append(validTimes, t)
+func (d *DataPoint) value(i int) float64 {
+	switch i {
+	case 0:
+		return d.Value0
+	case 1:
+		return d.Value1
+	case 2:
+		return d.Value2
+	case 3:
+		return d.Value3
+	case 4:
+		return d.Value4
 	}
+	return 0
+}
 
-	// Impostiamo il numero massimo di tick che vogliamo mostrare.
-	const maxTicks = 10
-	var ticks []plot.Tick
-
-	// Se il numero di timestamp validi è minore o uguale a maxTicks, li stampiamo tutti.
-	if len(validTimes) <= maxTicks {
-		for _, t := range validTimes {
-			ticks = append(ticks, plot.Tick{
-				Value: float64(t.Unix()),
-				Label: t.Format(tickFormat),
-			})
-		}
-		return ticks
-	}
+// timeBars implementa plot.Plotter disegnando una barra per punto, centrata sulla X e larga Width
+// (in secondi), con colori diversi per i valori positivi e negativi.
+type timeBars struct {
+	plotter.XYs
+	Width    float64
+	Positive color.Color
+	Negative color.Color
+}
 
-	// Se ci sono più timestamp, calcoliamo un intervallo per "saltare" alcuni tick.
-	step := len(validTimes) / maxTicks
-	if step < 1 {
-		step = 1
-	}
+func (b *timeBars) Plot(c draw.Canvas, plt *plot.Plot) {
+	trX, trY := plt.Transforms(&c)
+	for _, pt := range b.XYs {
+		x0, x1 := trX(pt.X-b.Width/2), trX(pt.X+b.Width/2)
+		y0, y1 := trY(0), trY(pt.Y)
 
-	// Selezioniamo ogni 'step'-esimo timestamp.
-	for i, t := range validTimes {
-		if i%step == 0 {
-			ticks = append(ticks, plot.Tick{
-				Value: float64(t.Unix()),
-				Label: t.Format(tickFormat),
-			})
+		fill := b.Positive
+		if pt.Y < 0 {
+			fill = b.Negative
 		}
+
+		poly := c.ClipPolygonXY([]vg.Point{{X: x0, Y: y0}, {X: x0, Y: y1}, {X: x1, Y: y1}, {X: x1, Y: y0}})
+		c.FillPolygon(fill, poly)
 	}
+}
 
-	// Assicuriamoci di includere anche l'ultimo timestamp, se non è già stato aggiunto.
-	last := validTimes[len(validTimes)-1]
-	if len(ticks) == 0 || ticks[len(ticks)-1].Value != float64(last.Unix()) {
-		ticks = append(ticks, plot.Tick{
-			Value: float64(last.Unix()),
-			Label: last.Format(tickFormat),
-		})
+func (b *timeBars) DataRange() (xmin, xmax, ymin, ymax float64) {
+	if len(b.XYs) == 0 {
+		return 0, 0, 0, 0
 	}
+	xmin, xmax, ymin, ymax = plotter.XYRange(b.XYs)
+	return xmin - b.Width/2, xmax + b.Width/2, min(ymin, 0), max(ymax, 0)
+}
 
+func (b *timeBars) Thumbnail(c *draw.Canvas) {
+	mid := (c.Min.X + c.Max.X) / 2
+	c.FillPolygon(b.Positive, c.ClipPolygonXY([]vg.Point{{X: c.Min.X, Y: c.Min.Y}, {X: c.Min.X, Y: c.Max.Y}, {X: mid, Y: c.Max.Y}, {X: mid, Y: c.Min.Y}}))
+	c.FillPolygon(b.Negative, c.ClipPolygonXY([]vg.Point{{X: mid, Y: c.Min.Y}, {X: mid, Y: c.Max.Y}, {X: c.Max.X, Y: c.Max.Y}, {X: c.Max.X, Y: c.Min.Y}}))
+}
+
+// relativeTimeTicks implementa plot.Ticker per un asse X espresso in secondi dall'inizio
+// di una finestra, etichettando i tick con le date della finestra che comincia in start.
+// I passi sono quelli di calendario di timeTicks, così da restare leggibili anche su più anni.
+type relativeTimeTicks struct {
+	start time.Time
+	l     *Locale
+}
+
+func (rt relativeTimeTicks) Ticks(min, max float64) []plot.Tick {
+	offset := float64(rt.start.Unix())
+	ticks := timeTicks{loc: rt.start.Location(), l: rt.l}.Ticks(min+offset, max+offset)
+	for i := range ticks {
+		ticks[i].Value -= offset
+	}
 	return ticks
 }
 
+// seriesColors restituisce i colori della palette da usare, in ordine, per serie diverse.
+func seriesColors(palette *bh.Palette) []color.Color {
+	return []color.Color{palette.Primary, palette.Blue, palette.Orange, palette.Green, palette.Purple, palette.Brown}
+}
+
 func setAxisColor(axis *plot.Axis, color color.Color) {
 	axis.Color = color
 	axis.Label.TextStyle.Color = color
@@ -109,12 +154,19 @@ func setAxisColor(axis *plot.Axis, color color.Color) {
 	axis.Tick.Label.Color = color
 }
 
-func newPlot(timestamps []time.Time, palette *bh.Palette) *plot.Plot {
+// setAxisLabel imposta l'etichetta di un asse con il font dei grafici.
+func setAxisLabel(axis *plot.Axis, text string) {
+	axis.Label.Text = text
+	axis.Label.TextStyle.Font = plotFont
+}
+
+// newPlot crea un grafico con i colori della palette e un asse X temporale etichettato nella lingua l.
+func newPlot(l *Locale, palette *bh.Palette) *plot.Plot {
 	p := plot.New()
 	p.BackgroundColor = color.Transparent
 	setAxisColor(&p.X, palette.Primary)
 	setAxisColor(&p.Y, palette.Primary)
-	p.X.Tick.Marker = customTimeTicks{times: timestamps}
+	p.X.Tick.Marker = timeTicks{loc: appLocation, l: l}
 	p.X.Tick.Label.Rotation = math.Pi / -2
 	p.X.Tick.Label.XAlign = 0.05
 	p.X.Tick.Label.YAlign = 0
@@ -126,76 +178,145 @@ func newPlot(timestamps []time.Time, palette *bh.Palette) *plot.Plot {
 	return p
 }
 
-func getPlotSVG(p *plot.Plot, w vg.Length, h vg.Length) (b []byte, err error) {
-	writer, err := p.WriterTo(w, h, "svg")
-	if err != nil {
-		err = errors.New("errore nella creazione del writer SVG: " + err.Error())
+// getPlotImage esporta il grafico nel formato richiesto. Per SVG il font viene sostituito con
+// fontFamily, mentre PNG, PDF ed EPS usano Liberation Sans, incorporata nel caso del PDF.
+func getPlotImage(p *plot.Plot, w vg.Length, h vg.Length, pf plotFormat) (b []byte, err error) {
+	w, h = pf.size(w, h)
+	var c interface {
+		vg.CanvasSizer
+		io.WriterTo
+	}
+	switch pf.Name {
+	case "svg":
+		c = vgsvg.New(w, h)
+	case "png":
+		c = vgimg.PngCanvas{Canvas: vgimg.NewWith(vgimg.UseWH(w, h), vgimg.UseDPI(pf.DPI))}
+	case "pdf":
+		pdf := vgpdf.New(w, h)
+		pdf.EmbedFonts(true)
+		c = pdf
+	case "eps":
+		c = vgeps.New(w, h)
+	default:
+		err = errors.New("formato non supportato: " + pf.Name)
 		return
 	}
 
+	if pf.Name != "svg" && pf.Background != nil {
+		p.BackgroundColor = pf.Background
+	}
+	p.Draw(draw.New(c))
+
 	var buf bytes.Buffer
-	_, err = writer.WriteTo(&buf)
+	_, err = c.WriteTo(&buf)
 	if err != nil {
-		err = errors.New("errore nella scrittura del plot SVG: " + err.Error())
+		err = errors.New("errore nella scrittura del plot " + strings.ToUpper(pf.Name) + ": " + err.Error())
 		return
 	}
 
-	b = bytes.ReplaceAll(buf.Bytes(), []byte("Liberation Sans"), []byte(fontFamily))
+	b = buf.Bytes()
+	if pf.Name == "svg" {
+		b = bytes.ReplaceAll(b, []byte("Liberation Sans"), []byte(fontFamily))
+	}
 	return
 }
 
-func plotMeasure(measure string, f, t *int64, palette *bh.Palette) (p *plot.Plot, err error) {
+// plotMeasure disegna una misura; se points è maggiore di 0 la serie viene ridotta a circa points punti.
+func plotMeasure(measure string, f, t *int64, points int, us UnitSystem, l *Locale, dec *decorations, palette *bh.Palette) (p *plot.Plot, err error) {
 	m := []string{measure}
 
-	dp, err := getDataPoints(m, f, t)
+	dp, err := us.dataPoints(m, f, t)
 	if err != nil {
 		err = errors.New("errore nella lettura dei dati: " + err.Error())
 		return
 	}
 
-	var timestamps []time.Time
 	pts := make(plotter.XYs, len(dp))
 	for i := range dp {
 		pts[i].X = dp[i].Dt
 		pts[i].Y = dp[i].Value0
-
-		timestamps = append(timestamps, time.Unix(int64(dp[i].Dt), 0).Round(time.Minute))
 	}
 
 	// Plot the data
-	p = newPlot(timestamps, palette)
+	p = newPlot(l, palette)
+	dec.background(p)
+	setAxisLabel(&p.Y, l.Label(us, measure))
 
-	addLines(p, pts, palette.Primary, false, capitalize(measure))
+	addTimeLines(p, pts, points, palette.Primary, false, l.MeasureName(measure))
 
 	return
 }
 
-func addLines(p *plot.Plot, points plotter.XYs, color color.Color, dashed bool, label string) error {
-	l, err := plotter.NewLine(points)
+func newLine(points plotter.XYs, color color.Color, dashed bool) (*plotter.Line, error) {
+	line, err := plotter.NewLine(points)
 	if err != nil {
-		return errors.New("Errore nella creazione del plot: " + err.Error())
+		return nil, errors.New("Errore nella creazione del plot: " + err.Error())
 	}
 
 	if dashed {
-		l.Dashes = []vg.Length{vg.Points(5), vg.Points(5)}
+		line.Dashes = []vg.Length{vg.Points(5), vg.Points(5)}
+	}
+	line.Color = color
+	return line, nil
+}
+
+func addLines(p *plot.Plot, points plotter.XYs, color color.Color, dashed bool, label string) error {
+	line, err := newLine(points, color, dashed)
+	if err != nil {
+		return err
 	}
-	l.Color = color
-	p.Add(l)
+	p.Add(line)
 
-	p.Legend.Add(label, l)
+	p.Legend.Add(label, line)
 	return nil
 }
 
-func plotTemperature(f, t *int64, palette *bh.Palette) (p *plot.Plot, err error) {
-	dp, err := getDataPoints([]string{"temp", "temp_min", "temp_max", "feels_like"}, f, t)
+// plotOverlay sovrappone la stessa finestra di calendario degli anni precedenti, allineata
+// per giorno dell'anno, su un asse X relativo all'inizio della finestra.
+func plotOverlay(measure string, f, t *int64, years int, us UnitSystem, l *Locale, palette *bh.Palette) (p *plot.Plot, err error) {
+	colors := seriesColors(palette)
+	start := localTime(*f)
+	end := localTime(*t)
+
+	p = newPlot(l, palette)
+	setAxisLabel(&p.Y, l.Label(us, measure))
+	p.X.Tick.Marker = relativeTimeTicks{start: start, l: l}
+	p.X.Min, p.X.Max = 0, end.Sub(start).Seconds()
+
+	for k := years; k >= 0; k-- {
+		from := start.AddDate(-k, 0, 0)
+		yf, yt := alignConstraints(from.Unix(), end.AddDate(-k, 0, 0).Unix())
+
+		var dp []DataPoint
+		dp, err = us.dataPoints([]string{measure}, yf, yt)
+		if err != nil {
+			err = errors.New("errore nella lettura dei dati: " + err.Error())
+			return
+		}
+
+		pts := make(plotter.XYs, len(dp))
+		for i := range dp {
+			pts[i].X = dp[i].Dt - float64(from.Unix())
+			pts[i].Y = dp[i].Value0
+		}
+
+		err = addTimeLines(p, pts, 0, colors[k], k > 0, strconv.Itoa(from.Year()))
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func plotTemperature(f, t *int64, points int, us UnitSystem, l *Locale, dec *decorations, palette *bh.Palette) (p *plot.Plot, err error) {
+	dp, err := us.dataPoints([]string{"temp", "temp_min", "temp_max", "feels_like"}, f, t)
 	if err != nil {
 		err = errors.New("errore nella lettura dei dati: " + err.Error())
 		return
 	}
 
 	// Plot the data
-	var timestamps []time.Time
-
 	tPts := make(plotter.XYs, len(dp))
 	tMinPts := make(plotter.XYs, len(dp))
 	tMaxPts := make(plotter.XYs, len(dp))
@@ -210,26 +331,26 @@ func plotTemperature(f, t *int64, palette *bh.Palette) (p *plot.Plot, err error)
 		tMinPts[i].Y = dp[i].Value1
 		tMaxPts[i].Y = dp[i].Value2
 		flPts[i].Y = dp[i].Value3
-
-		timestamps = append(timestamps, time.Unix(int64(dp[i].Dt), 0).Round(time.Minute))
 	}
 
-	p = newPlot(timestamps, palette)
+	p = newPlot(l, palette)
+	dec.background(p)
+	setAxisLabel(&p.Y, l.Label(us, "temp"))
 
 	// Add the plot points to the plot
-	err = addLines(p, flPts, palette.Orange, true, "Feels Like")
+	err = addTimeLines(p, flPts, points, palette.Orange, true, l.T("Feels Like"))
 	if err != nil {
 		return
 	}
-	err = addLines(p, tPts, palette.Primary, false, "Temp")
+	err = addTimeLines(p, tPts, points, palette.Primary, false, l.T("Temp"))
 	if err != nil {
 		return
 	}
-	err = addLines(p, tMinPts, palette.Blue, false, "Min")
+	err = addTimeLines(p, tMinPts, points, palette.Blue, false, l.T("Min"))
 	if err != nil {
 		return
 	}
-	err = addLines(p, tMaxPts, palette.Red, false, "Max")
+	err = addTimeLines(p, tMaxPts, points, palette.Red, false, l.T("Max"))
 	if err != nil {
 		return
 	}
@@ -237,14 +358,13 @@ func plotTemperature(f, t *int64, palette *bh.Palette) (p *plot.Plot, err error)
 	return
 }
 
-func plotPressure(f, t *int64, palette *bh.Palette) (p *plot.Plot, err error) {
-	dp, err := getDataPoints([]string{"sea_level", "grnd_level"}, f, t)
+func plotPressure(f, t *int64, points int, us UnitSystem, l *Locale, dec *decorations, palette *bh.Palette) (p *plot.Plot, err error) {
+	dp, err := us.dataPoints([]string{"sea_level", "grnd_level"}, f, t)
 	if err != nil {
 		err = errors.New("errore nella lettura dei dati: " + err.Error())
 		return
 	}
 
-	var timestamps []time.Time
 	slPts := make(plotter.XYs, len(dp))
 	grPts := make(plotter.XYs, len(dp))
 	for i := range dp {
@@ -254,17 +374,17 @@ func plotPressure(f, t *int64, palette *bh.Palette) (p *plot.Plot, err error) {
 
 		grPts[i].X = dp[i].Dt
 		grPts[i].Y = dp[i].Value1
-
-		timestamps = append(timestamps, time.Unix(int64(dp[i].Dt), 0).Round(time.Minute))
 	}
 
-	p = newPlot(timestamps, palette)
+	p = newPlot(l, palette)
+	dec.background(p)
+	setAxisLabel(&p.Y, l.Label(us, "pressure"))
 
-	err = addLines(p, slPts, palette.Blue, false, "Sea Level")
+	err = addTimeLines(p, slPts, points, palette.Blue, false, l.T("Sea Level"))
 	if err != nil {
 		return
 	}
-	err = addLines(p, grPts, palette.Brown, false, "Ground Level")
+	err = addTimeLines(p, grPts, points, palette.Brown, false, l.T("Ground Level"))
 	if err != nil {
 		return
 	}
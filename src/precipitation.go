@@ -0,0 +1,141 @@
+package src
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	periodDay   = "day"
+	periodWeek  = "week"
+	periodMonth = "month"
+
+	// Tempo asciutto minimo per considerare concluso un evento di pioggia
+	eventDryGap = time.Hour
+)
+
+type PrecipitationTotal struct {
+	Period string  `json:"period"`
+	Start  int64   `json:"start"`
+	Rain   float64 `json:"rain"`
+	Snow   float64 `json:"snow"`
+	Total  float64 `json:"total"`
+}
+
+type PrecipitationEvent struct {
+	Start int64   `json:"start"`
+	End   int64   `json:"end"`
+	Total float64 `json:"total"`
+	Peak  float64 `json:"peak"`
+}
+
+type Precipitation struct {
+	Period string               `json:"period"`
+	Unit   string               `json:"unit"`
+	Rain   float64              `json:"rain"`
+	Snow   float64              `json:"snow"`
+	Total  float64              `json:"total"`
+	Totals []PrecipitationTotal `json:"totals"`
+	Events []PrecipitationEvent `json:"events"`
+}
+
+// periodStart restituisce l'inizio del periodo (giorno, settimana ISO o mese) che contiene t e la sua etichetta.
+func periodStart(t time.Time, period string) (time.Time, string, error) {
+	d := dayStart(t)
+	switch period {
+	case periodDay:
+		return d, d.Format(dateFormat), nil
+	case periodWeek:
+		d = d.AddDate(0, 0, -(int(d.Weekday())+6)%7)
+		y, w := d.ISOWeek()
+		return d, fmt.Sprintf("%d-W%02d", y, w), nil
+	case periodMonth:
+		d = d.AddDate(0, 0, 1-d.Day())
+		return d, d.Format("2006-01"), nil
+	}
+	return t, "", errors.New("periodo non supportato: " + period)
+}
+
+// computePrecipitation integra le intensità orarie di pioggia e neve (Value0 e Value1, in mm/h)
+// sugli intervalli tra i campioni con il metodo dei trapezi, ignorando le interruzioni più lunghe
+// di tre esecuzioni del cron, e individua gli eventi di pioggia.
+func computePrecipitation(dp []DataPoint, period string) (p Precipitation, err error) {
+	p = Precipitation{Period: period, Totals: []PrecipitationTotal{}, Events: []PrecipitationEvent{}}
+	if _, _, err = periodStart(time.Now().In(appLocation), period); err != nil {
+		return
+	}
+
+	var event *PrecipitationEvent
+	closeEvent := func() {
+		if event != nil {
+			p.Events = append(p.Events, *event)
+			event = nil
+		}
+	}
+
+	for i := 1; i < len(dp); i++ {
+		prev, cur := dp[i-1], dp[i]
+		dt := cur.Dt - prev.Dt
+		if dt <= 0 || isGap(dt) {
+			closeEvent()
+			continue
+		}
+
+		start, label, _ := periodStart(localTime(int64(prev.Dt)), period)
+		if len(p.Totals) == 0 || p.Totals[len(p.Totals)-1].Period != label {
+			p.Totals = append(p.Totals, PrecipitationTotal{Period: label, Start: start.Unix()})
+		}
+		total := &p.Totals[len(p.Totals)-1]
+
+		hours := dt / 3600
+		rain := (prev.Value0 + cur.Value0) / 2 * hours
+		snow := (prev.Value1 + cur.Value1) / 2 * hours
+		total.Rain += rain
+		total.Snow += snow
+		total.Total += rain + snow
+		p.Rain += rain
+		p.Snow += snow
+		p.Total += rain + snow
+
+		// Rilevamento degli eventi: un evento si chiude dopo un periodo asciutto di almeno eventDryGap
+		if rain+snow > 0 {
+			if event == nil {
+				event = &PrecipitationEvent{Start: int64(prev.Dt)}
+			}
+			event.Total += rain + snow
+			event.Peak = max(event.Peak, prev.Value0+prev.Value1, cur.Value0+cur.Value1)
+			event.End = int64(cur.Dt)
+		} else if event != nil && int64(cur.Dt)-event.End >= int64(eventDryGap.Seconds()) {
+			closeEvent()
+		}
+	}
+	closeEvent()
+
+	return
+}
+
+// convert converte le quantità nelle unità del sistema scelto; l'intensità massima degli eventi è una velocità (mm/h).
+func (p *Precipitation) convert(us UnitSystem) {
+	u, _ := us.unit(quantityPrecipitation)
+	p.Unit = u.Symbol
+	p.Rain, p.Snow, p.Total = u.Convert(p.Rain), u.Convert(p.Snow), u.Convert(p.Total)
+	for i := range p.Totals {
+		t := &p.Totals[i]
+		t.Rain, t.Snow, t.Total = u.Convert(t.Rain), u.Convert(t.Snow), u.Convert(t.Total)
+	}
+	for i := range p.Events {
+		e := &p.Events[i]
+		e.Total = u.Convert(e.Total)
+		e.Peak = us.Convert("rain_1h", e.Peak)
+	}
+}
+
+func getPrecipitation(f, t *int64, period string) (p Precipitation, err error) {
+	dp, err := getDataPoints([]string{"rain_1h", "snow_1h"}, f, t)
+	if err != nil {
+		err = errors.New("errore nella lettura dei dati: " + err.Error())
+		return
+	}
+	return computePrecipitation(dp, period)
+}
@@ -0,0 +1,107 @@
+package src
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestComputePrecipitation(t *testing.T) {
+	cronInterval = 1800
+
+	start := time.Date(2025, 3, 10, 22, 0, 0, 0, appLocation)
+	// Intensità in mm/h ogni 30 minuti: due eventi separati da più di un'ora asciutta
+	rates := []float64{0, 2, 4, 0, 0, 0, 0, 1, 1, 0}
+	var dp []DataPoint
+	for i, r := range rates {
+		dp = append(dp, DataPoint{Dt: float64(start.Add(time.Duration(i) * 30 * time.Minute).Unix()), Value0: r})
+	}
+
+	p, err := computePrecipitation(dp, periodDay)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if math.Abs(p.Total-4) > 1e-9 {
+		t.Errorf("expected 4mm in total, got %.3f", p.Total)
+	}
+	if len(p.Totals) != 2 || math.Abs(p.Totals[0].Total-3) > 1e-9 || math.Abs(p.Totals[1].Total-1) > 1e-9 {
+		t.Errorf("unexpected daily totals: %+v", p.Totals)
+	}
+
+	if len(p.Events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(p.Events))
+	}
+	if p.Events[0].Peak != 4 || math.Abs(p.Events[0].Total-3) > 1e-9 {
+		t.Errorf("unexpected first event: %+v", p.Events[0])
+	}
+	if p.Events[0].Start != int64(dp[0].Dt) || p.Events[0].End != int64(dp[3].Dt) {
+		t.Errorf("unexpected first event bounds: %+v", p.Events[0])
+	}
+
+	if _, err := computePrecipitation(dp, "fortnight"); err == nil {
+		t.Error("expected an error for an unknown period")
+	}
+}
+
+func TestPeriodStart(t *testing.T) {
+	d := time.Date(2025, 1, 1, 15, 0, 0, 0, appLocation) // mercoledì
+	tests := map[string]string{periodDay: "2025-01-01", periodWeek: "2025-W01", periodMonth: "2025-01"}
+	for period, want := range tests {
+		_, got, err := periodStart(d, period)
+		if err != nil || got != want {
+			t.Errorf("periodStart(%s) = %q, %v; want %q", period, got, err, want)
+		}
+	}
+}
+
+func TestGetPrecipitationParams(t *testing.T) {
+	tests := map[string]string{"": periodDay, "period=week": periodWeek, "period=month": periodMonth, "period=fortnight": ""}
+	for query, want := range tests {
+		period, err := getPrecipitationParams(httptest.NewRequest(http.MethodGet, "/api/precipitation?"+query, nil))
+		if (err != nil) != (want == "") || period != want && want != "" {
+			t.Errorf("%q: got %q, %v; want %q", query, period, err, want)
+		}
+	}
+}
+
+func TestGetAPIPrecipitation(t *testing.T) {
+	cronInterval = 1800
+	defer func(old []string) { measures = old }(measures)
+	measures = []string{"rain_1h", "snow_1h"}
+
+	start := time.Date(2025, 3, 10, 0, 0, 0, 0, appLocation)
+	end := start.AddDate(0, 0, 14)
+	f, to := alignConstraints(start.Unix(), end.Unix())
+	dpCache.Add(getKey(measures, f, to), []DataPoint{
+		{Dt: float64(start.Unix()), Value0: 2},
+		{Dt: float64(start.Add(30 * time.Minute).Unix()), Value0: 2},
+	})
+
+	tests := map[string]int{"week": http.StatusOK, "month": http.StatusOK, "": http.StatusOK, "fortnight": http.StatusBadRequest}
+	for period, status := range tests {
+		url := fmt.Sprintf("/api/precipitation?period=%s&from=%d&to=%d", period, start.Unix(), end.Unix())
+		w := httptest.NewRecorder()
+		getAPIPrecipitation(w, httptest.NewRequest(http.MethodGet, url, nil))
+		if w.Code != status {
+			t.Errorf("period=%s: status %d, want %d (%s)", period, w.Code, status, w.Body.String())
+			continue
+		}
+		if status != http.StatusOK {
+			continue
+		}
+
+		var p Precipitation
+		if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+			t.Fatal(err)
+		}
+		if want := cmp.Or(period, periodDay); p.Period != want || math.Abs(p.Rain-1) > 1e-9 {
+			t.Errorf("period=%s: got period %q and rain %v", period, p.Period, p.Rain)
+		}
+	}
+}
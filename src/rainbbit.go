@@ -6,6 +6,7 @@ import (
 	"os"
 	"strconv"
 	"time"
+	_ "time/tzdata" // l'immagine Docker non contiene il database dei fusi orari
 
 	"github.com/briandowns/openweathermap"
 	"github.com/joho/godotenv"
@@ -14,14 +15,17 @@ import (
 
 // Impostazioni per unità e lingua
 const (
-	unit    = "C"
-	lang    = "it"
+	unit    = "C"  // i dati sono sempre salvati in unità metriche, convertite solo in output
+	lang    = "it" // usata solo da OpenWeatherMap: le condizioni vengono tradotte dai cataloghi in locales
 	address = ":3000"
 )
 
 var (
 	cronInterval int64
 	zone         string
+
+	// Fuso orario usato per giorni, settimane e tick dei grafici (APP_TIMEZONE, altrimenti quello di sistema)
+	appLocation = time.Local
 )
 
 // ------------------------
@@ -34,6 +38,20 @@ func Main() {
 		log.Println("Nessun file .env trovato, verranno usate le variabili d'ambiente di sistema")
 	}
 
+	// Fuso orario usato per giorni, settimane e tick dei grafici
+	if tz := os.Getenv("APP_TIMEZONE"); tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			log.Fatalln("Errore nel caricamento di APP_TIMEZONE:", err)
+		}
+		appLocation = loc
+	}
+
+	// Risoluzione predefinita dei grafici PNG
+	if err = loadPlotDPI(); err != nil {
+		log.Fatalln("Errore nella configurazione dei grafici:", err)
+	}
+
 	// Lettura delle variabili d'ambiente necessarie
 	apiKey := os.Getenv("OWM_API_KEY")
 	latitudeStr := os.Getenv("OWM_LATITUDE")
@@ -64,7 +82,7 @@ func Main() {
 
 	// Creazione e configurazione del cron scheduler
 	spec := getEnvDefault("OWM_CRON", "0 0/30 * * * *")
-	c := cron.New(cron.WithSeconds())
+	c := cron.New(cron.WithSeconds(), cron.WithLocation(appLocation))
 	_, err = c.AddFunc(spec, func() {
 		log.Println("Eseguo fetchAndSaveWeather")
 		fetchAndSaveWeather(db, coords)
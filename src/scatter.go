@@ -0,0 +1,136 @@
+package src
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"math"
+
+	bh "github.com/birabittoh/bunnyhue"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+const colorByTime = "time"
+
+// linearRegression calcola la retta dei minimi quadrati y = slope*x + intercept e il coefficiente R².
+func linearRegression(pts plotter.XYs) (slope, intercept, r2 float64) {
+	n := float64(len(pts))
+	if n < 2 {
+		return math.NaN(), math.NaN(), math.NaN()
+	}
+
+	var sx, sy float64
+	for _, p := range pts {
+		sx += p.X
+		sy += p.Y
+	}
+	mx, my := sx/n, sy/n
+
+	var sxx, sxy, syy float64
+	for _, p := range pts {
+		sxx += (p.X - mx) * (p.X - mx)
+		sxy += (p.X - mx) * (p.Y - my)
+		syy += (p.Y - my) * (p.Y - my)
+	}
+	if sxx == 0 {
+		return math.NaN(), math.NaN(), math.NaN()
+	}
+
+	slope = sxy / sxx
+	intercept = my - slope*mx
+	if syy == 0 {
+		return slope, intercept, 1
+	}
+	r2 = sxy * sxy / (sxx * syy)
+	return
+}
+
+// resetValueAxis riporta un asse di newPlot a tick numerici orizzontali.
+func resetValueAxis(axis *plot.Axis) {
+	axis.Tick.Marker = plot.DefaultTicks{}
+	axis.Tick.Label.Rotation = 0
+	axis.Tick.Label.XAlign = draw.XCenter
+	axis.Tick.Label.YAlign = draw.YTop
+}
+
+// plotScatter disegna la misura y in funzione della misura x, colorando i punti in base al tempo
+// o a una terza misura (colorBy), con la retta di regressione e il suo R² in legenda.
+func plotScatter(x, y, colorBy string, f, t *int64, us UnitSystem, l *Locale, palette *bh.Palette) (p *plot.Plot, err error) {
+	m := []string{x, y}
+	if colorBy != "" && colorBy != colorByTime {
+		m = append(m, colorBy)
+	}
+
+	dp, err := us.dataPoints(m, f, t)
+	if err != nil {
+		err = errors.New("errore nella lettura dei dati: " + err.Error())
+		return
+	}
+
+	pts := make(plotter.XYs, len(dp))
+	values := make([]float64, len(dp))
+	for i := range dp {
+		pts[i].X = dp[i].Value0
+		pts[i].Y = dp[i].Value1
+		switch colorBy {
+		case colorByTime:
+			values[i] = dp[i].Dt
+		default:
+			values[i] = dp[i].Value2
+		}
+	}
+
+	p = newPlot(l, palette)
+	resetValueAxis(&p.X)
+	setAxisLabel(&p.X, l.Label(us, x))
+	setAxisLabel(&p.Y, l.Label(us, y))
+
+	s, err := plotter.NewScatter(pts)
+	if err != nil {
+		err = errors.New("errore nella creazione del plot: " + err.Error())
+		return
+	}
+	s.GlyphStyle = draw.GlyphStyle{Color: palette.Blue, Radius: vg.Points(1.5), Shape: draw.CircleGlyph{}}
+
+	if colorBy != "" && len(values) > 0 {
+		stops := []color.Color{palette.Blue, palette.Green, palette.Orange, palette.Red}
+		lo, hi := values[0], values[0]
+		for _, v := range values {
+			lo = min(lo, v)
+			hi = max(hi, v)
+		}
+		scale := func(v float64) float64 {
+			if hi == lo {
+				return 0.5
+			}
+			return (v - lo) / (hi - lo)
+		}
+		s.GlyphStyleFunc = func(i int) draw.GlyphStyle {
+			gs := s.GlyphStyle
+			gs.Color = colorScale(stops, scale(values[i]))
+			return gs
+		}
+
+		label := func(v float64) string {
+			if colorBy == colorByTime {
+				return localTime(int64(v)).Format(tickFormat)
+			}
+			return l.MeasureName(colorBy) + " " + us.Info(colorBy).Format(v)
+		}
+		p.Legend.Add(label(lo), legendThumb{stops[0]})
+		p.Legend.Add(label(hi), legendThumb{stops[len(stops)-1]})
+	}
+	p.Add(s)
+
+	slope, intercept, r2 := linearRegression(pts)
+	if !math.IsNaN(slope) {
+		xmin, xmax, _, _ := plotter.XYRange(pts)
+		line := plotter.XYs{{X: xmin, Y: slope*xmin + intercept}, {X: xmax, Y: slope*xmax + intercept}}
+		err = addLines(p, line, palette.Primary, true, fmt.Sprintf("y = %.3gx %+.3g (R² = %.3f)", slope, intercept, r2))
+	}
+
+	return
+}
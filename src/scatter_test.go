@@ -0,0 +1,24 @@
+package src
+
+import (
+	"math"
+	"testing"
+
+	"gonum.org/v1/plot/plotter"
+)
+
+func TestLinearRegression(t *testing.T) {
+	slope, intercept, r2 := linearRegression(plotter.XYs{{X: 0, Y: 1}, {X: 1, Y: 3}, {X: 2, Y: 5}})
+	if slope != 2 || intercept != 1 || r2 != 1 {
+		t.Errorf("got y = %vx + %v (R² %v), want y = 2x + 1 (R² 1)", slope, intercept, r2)
+	}
+
+	_, _, r2 = linearRegression(plotter.XYs{{X: 0, Y: 0}, {X: 1, Y: 1}, {X: 2, Y: 0}, {X: 3, Y: 1}})
+	if math.Abs(r2-0.2) > 1e-9 {
+		t.Errorf("got R² %v, want 0.2", r2)
+	}
+
+	if slope, _, _ := linearRegression(plotter.XYs{{X: 1, Y: 1}, {X: 1, Y: 2}}); !math.IsNaN(slope) {
+		t.Errorf("expected NaN slope for a vertical line, got %v", slope)
+	}
+}
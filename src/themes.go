@@ -0,0 +1,170 @@
+package src
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/color"
+	"log"
+	"maps"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	bh "github.com/birabittoh/bunnyhue"
+)
+
+const (
+	themeAuto = "auto" // segue prefers-color-scheme del browser, scegliendo tra il tema predefinito e quello chiaro
+
+	// Rapporti di contrasto minimi (WCAG 2.1): testo normale e elementi grafici
+	minTextContrast    = 4.5
+	minGraphicContrast = 3
+)
+
+// themeConfig è la definizione di un tema nel file di configurazione: "base" indica la palette
+// di partenza ("dark" o "light"), le altre chiavi sono i nomi dei colori (background, contrast,
+// primary, secondary e i colori delle serie), nella forma #rrggbb o #rgb.
+type themeConfig map[string]string
+
+// paletteFields associa i nomi dei colori ai campi della palette.
+func paletteFields(p *bh.Palette) map[string]*color.Color {
+	return map[string]*color.Color{
+		"background": &p.Background,
+		"contrast":   &p.Contrast,
+		"primary":    &p.Primary,
+		"secondary":  &p.Secondary,
+		"blue":       &p.Blue,
+		"red":        &p.Red,
+		"green":      &p.Green,
+		"pink":       &p.Pink,
+		"purple":     &p.Purple,
+		"cyan":       &p.Cyan,
+		"orange":     &p.Orange,
+		"teal":       &p.Teal,
+		"brown":      &p.Brown,
+		"lime":       &p.Lime,
+	}
+}
+
+func parseHexColor(s string) (color.Color, error) {
+	hex, ok := strings.CutPrefix(s, "#")
+	if ok && len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if !ok || len(hex) != 6 || err != nil {
+		return nil, errors.New("colore non valido: " + s)
+	}
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 255}, nil
+}
+
+// luminance restituisce la luminanza relativa di un colore, come definita dalle WCAG.
+func luminance(c color.Color) float64 {
+	r, g, b, _ := c.RGBA()
+	channel := func(v uint32) float64 {
+		s := float64(v) / 0xffff
+		if s <= 0.03928 {
+			return s / 12.92
+		}
+		return math.Pow((s+0.055)/1.055, 2.4)
+	}
+	return 0.2126*channel(r) + 0.7152*channel(g) + 0.0722*channel(b)
+}
+
+// contrastRatio restituisce il rapporto di contrasto tra due colori, da 1 a 21.
+func contrastRatio(a, b color.Color) float64 {
+	la, lb := luminance(a), luminance(b)
+	return (max(la, lb) + 0.05) / (min(la, lb) + 0.05)
+}
+
+// newPalette costruisce la palette di un tema partendo dalla sua palette base.
+func (tc themeConfig) newPalette(name string) (*bh.Palette, error) {
+	base, ok := map[string]bh.Palette{"": bh.Dark, "dark": bh.Dark, "light": bh.Light}[tc["base"]]
+	if !ok {
+		return nil, errors.New("palette base non valida: " + tc["base"])
+	}
+
+	p := base
+	p.Name = name
+	fields := paletteFields(&p)
+	for key, value := range tc {
+		if key == "base" {
+			continue
+		}
+		field, ok := fields[key]
+		if !ok {
+			return nil, errors.New("colore sconosciuto: " + key)
+		}
+		c, err := parseHexColor(value)
+		if err != nil {
+			return nil, err
+		}
+		*field = c
+	}
+	return &p, nil
+}
+
+// validatePalette controlla che il testo sia leggibile sullo sfondo e sulle card; per gli altri
+// colori (link e serie dei grafici) un contrasto insufficiente viene solo segnalato.
+func validatePalette(p *bh.Palette) error {
+	text := map[string]color.Color{"primary": p.Primary, "secondary": p.Secondary}
+	for _, bg := range []struct {
+		name  string
+		color color.Color
+	}{{"background", p.Background}, {"contrast", p.Contrast}} {
+		for _, name := range slices.Sorted(maps.Keys(text)) {
+			if r := contrastRatio(text[name], bg.color); r < minTextContrast {
+				return fmt.Errorf("contrasto insufficiente tra %s e %s: %.2f, minimo %.1f", name, bg.name, r, minTextContrast)
+			}
+		}
+	}
+
+	for name, c := range paletteFields(p) {
+		if text[name] != nil || name == "background" || name == "contrast" {
+			continue
+		}
+		if r := contrastRatio(*c, p.Background); r < minGraphicContrast {
+			log.Printf("Tema %s: contrasto basso tra %s e background (%.2f)\n", p.Name, name, r)
+		}
+	}
+	return nil
+}
+
+// loadThemes registra i temi definiti nel file indicato da THEMES_FILE, se presente.
+// I temi integrati non possono essere ridefiniti.
+func loadThemes() error {
+	path := getEnvDefault("THEMES_FILE", "themes.json")
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var configs map[string]themeConfig
+	if err = json.Unmarshal(b, &configs); err != nil {
+		return errors.New("errore nella lettura di " + path + ": " + err.Error())
+	}
+
+	for name, tc := range configs {
+		if _, ok := palettes[name]; ok || name == "" || name == themeAuto {
+			return errors.New("nome del tema riservato: " + name)
+		}
+
+		p, err := tc.newPalette(name)
+		if err == nil {
+			err = validatePalette(p)
+		}
+		if err != nil {
+			return fmt.Errorf("tema %s: %w", name, err)
+		}
+		palettes[name] = p
+	}
+
+	log.Printf("%d temi personalizzati caricati da %s\n", len(configs), path)
+	return nil
+}
@@ -0,0 +1,117 @@
+package src
+
+import (
+	"html/template"
+	"image/color"
+	"math"
+	"net/url"
+	"strings"
+	"testing"
+
+	bh "github.com/birabittoh/bunnyhue"
+)
+
+func TestParseHexColor(t *testing.T) {
+	tests := []struct {
+		s    string
+		want color.Color
+		err  bool
+	}{
+		{"#1e90ff", color.RGBA{R: 30, G: 144, B: 255, A: 255}, false},
+		{"#FFF", color.RGBA{R: 255, G: 255, B: 255, A: 255}, false},
+		{"1e90ff", nil, true},
+		{"#1e90f", nil, true},
+		{"#zzzzzz", nil, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseHexColor(tt.s)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%s: expected an error", tt.s)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s: got %v (%v), want %v", tt.s, got, err, tt.want)
+		}
+	}
+}
+
+func TestContrastRatio(t *testing.T) {
+	black := color.RGBA{A: 255}
+	white := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	if r := contrastRatio(black, white); math.Abs(r-21) > 0.01 {
+		t.Errorf("black on white: got %.2f, want 21", r)
+	}
+	if r := contrastRatio(white, white); r != 1 {
+		t.Errorf("white on white: got %.2f, want 1", r)
+	}
+	if r := contrastRatio(bh.Dark.Primary, bh.Dark.Background); r < minTextContrast {
+		t.Errorf("dark palette: got %.2f", r)
+	}
+}
+
+func TestNewPalette(t *testing.T) {
+	p, err := themeConfig{"base": "light", "background": "#fdf6e3", "blue": "#1f5fa8"}.newPalette("paper")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name != "paper" || p.Background != (color.RGBA{R: 0xfd, G: 0xf6, B: 0xe3, A: 255}) || p.Primary != bh.Light.Primary {
+		t.Errorf("unexpected palette: %+v", p)
+	}
+	if err := validatePalette(p); err != nil {
+		t.Errorf("unexpected validation error: %v", err)
+	}
+
+	for _, tc := range []themeConfig{
+		{"base": "sepia"},
+		{"magenta": "#ff00ff"},
+		{"primary": "red"},
+	} {
+		if _, err := tc.newPalette("invalid"); err == nil {
+			t.Errorf("%v: expected an error", tc)
+		}
+	}
+
+	p, _ = themeConfig{"primary": "#333333"}.newPalette("unreadable")
+	if err := validatePalette(p); err == nil {
+		t.Error("expected a contrast error for dark text on a dark background")
+	}
+}
+
+func TestNewPlotImage(t *testing.T) {
+	pi := newPlotImage("/api/temp?theme=auto&from=10", "alt")
+	if pi.URL != "/api/temp?from=10&theme=" || pi.LightURL != "/api/temp?from=10&theme=light" {
+		t.Errorf("auto theme: got %q and %q", pi.URL, pi.LightURL)
+	}
+
+	pi = newPlotImage("/api/temp?theme=light&from=10", "alt")
+	if pi.URL != "/api/temp?theme=light&from=10" || pi.LightURL != "" {
+		t.Errorf("light theme: got %q and %q", pi.URL, pi.LightURL)
+	}
+}
+
+func TestGetTheme(t *testing.T) {
+	defer func(old []string) { themes = old }(themes)
+	themes = []string{"", "light", themeAuto}
+	tests := map[string]string{"": "", "light": "light", "auto": "auto", "x&w=2400&h=1600": ""}
+	for theme, want := range tests {
+		if got := getTheme(url.Values{"theme": {theme}}); got != want {
+			t.Errorf("getTheme(%q) = %q, want %q", theme, got, want)
+		}
+	}
+}
+
+func TestPlotPictureEscaping(t *testing.T) {
+	tmpl := template.Must(template.New("test").Funcs(funcMap).ParseFS(embeddedAssets, basePath))
+	template.Must(tmpl.Parse(`{{ define "test" }}{{ template "plotPicture" (plotImage (printf "/api/temp?from=%s&theme=%s" (urlquery .) "") "alt") }}{{ end }}`))
+
+	var b strings.Builder
+	if err := tmpl.ExecuteTemplate(&b, "test", "x&w=2400"); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(b.String(), "&amp;w=2400") || !strings.Contains(b.String(), "from=x%26w%3D2400") {
+		t.Errorf("parameters not escaped: %s", b.String())
+	}
+}
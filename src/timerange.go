@@ -0,0 +1,142 @@
+package src
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultRange = 24 * time.Hour
+	inputLayout  = "2006-01-02T15:04" // formato dei campi datetime-local
+)
+
+var (
+	// Espressioni relative: -7d, +2h, now-1M (m minuti, h ore, d giorni, w settimane, M mesi, y anni).
+	// Lo spazio vale come "+", che in una query string non codificata diventa uno spazio.
+	relativeRe = regexp.MustCompile(`^(?:now)?([+ -])(\d+)([mhdwMy])$`)
+	weekRe     = regexp.MustCompile(`^(\d{4})-W(\d{2})$`)
+
+	// Formati ISO-8601 accettati per from e to, dal più al meno preciso
+	isoLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", inputLayout, time.DateOnly}
+)
+
+// parseTime interpreta un estremo dell'intervallo: secondi Unix, "now", un'espressione relativa
+// a now o una data ISO-8601 nel fuso orario locale. Una data senza ora indica l'inizio del giorno,
+// o la sua fine se end è vero, così che to=2024-06-30 includa tutto il 30 giugno.
+func parseTime(s string, now time.Time, end bool) (time.Time, error) {
+	if s == "now" {
+		return now, nil
+	}
+	if m := relativeRe.FindStringSubmatch(s); m != nil {
+		n, err := strconv.Atoi(m[2])
+		if err != nil {
+			return time.Time{}, errors.New("espressione relativa non valida: " + s)
+		}
+		if m[1] == "-" {
+			n = -n
+		}
+		switch m[3] {
+		case "m":
+			return now.Add(time.Duration(n) * time.Minute), nil
+		case "h":
+			return now.Add(time.Duration(n) * time.Hour), nil
+		case "d":
+			return now.AddDate(0, 0, n), nil
+		case "w":
+			return now.AddDate(0, 0, 7*n), nil
+		case "M":
+			return now.AddDate(0, n, 0), nil
+		default:
+			return now.AddDate(n, 0, 0), nil
+		}
+	}
+	if v, err := strconv.ParseInt(s, 10, 64); err == nil && v >= 0 {
+		return localTime(v), nil
+	}
+	for _, layout := range isoLayouts {
+		t, err := time.ParseInLocation(layout, s, appLocation)
+		if err != nil {
+			continue
+		}
+		if end && layout == time.DateOnly {
+			t = t.AddDate(0, 0, 1).Add(-time.Second)
+		}
+		return t, nil
+	}
+	return time.Time{}, errors.New("data non valida: " + s)
+}
+
+// parsePeriod restituisce l'inizio e la fine (inclusa) di un periodo con nome:
+// un anno (2024), un mese (2024-06), un giorno (2024-06-15) o una settimana ISO (2024-W12).
+func parsePeriod(s string) (from, to time.Time, err error) {
+	if m := weekRe.FindStringSubmatch(s); m != nil {
+		year, _ := strconv.Atoi(m[1])
+		week, _ := strconv.Atoi(m[2])
+
+		// La settimana 1 è quella che contiene il 4 gennaio
+		jan4 := time.Date(year, time.January, 4, 0, 0, 0, 0, appLocation)
+		from = jan4.AddDate(0, 0, -(int(jan4.Weekday())+6)%7+7*(week-1))
+		if y, w := from.ISOWeek(); y != year || w != week {
+			return from, to, errors.New("settimana non valida: " + s)
+		}
+		return from, from.AddDate(0, 0, 7).Add(-time.Second), nil
+	}
+
+	for _, p := range []struct {
+		layout              string
+		years, months, days int
+	}{{"2006", 1, 0, 0}, {"2006-01", 0, 1, 0}, {time.DateOnly, 0, 0, 1}} {
+		from, err = time.ParseInLocation(p.layout, s, appLocation)
+		if err == nil {
+			return from, from.AddDate(p.years, p.months, p.days).Add(-time.Second), nil
+		}
+	}
+	return from, to, errors.New("periodo non valido: " + s)
+}
+
+// rangeParams restituisce from e to da passare ai grafici: gli estremi richiesti, risolti in secondi Unix
+// così da poterli inserire negli URL senza codifica (es. il "+" di una data ISO-8601 con fuso orario).
+// Gli estremi non richiesti restano vuoti, così che i grafici usino i valori predefiniti.
+func rangeParams(q url.Values, from, to time.Time) (f, t string) {
+	period := q.Get("period") != ""
+	if period || q.Get("from") != "" {
+		f = strconv.FormatInt(from.Unix(), 10)
+	}
+	if period || q.Get("to") != "" {
+		t = strconv.FormatInt(to.Unix(), 10)
+	}
+	return
+}
+
+// parseRange restituisce l'intervallo richiesto con period oppure con from e to;
+// senza parametri, le ultime 24 ore.
+func parseRange(q url.Values, now time.Time) (from, to time.Time, err error) {
+	if period := q.Get("period"); period != "" {
+		if q.Get("from") != "" || q.Get("to") != "" {
+			return from, to, errors.New("period non può essere usato insieme a from e to")
+		}
+		return parsePeriod(period)
+	}
+
+	to = now
+	if s := q.Get("to"); s != "" {
+		if to, err = parseTime(s, now, true); err != nil {
+			return
+		}
+	}
+	from = to.Add(-defaultRange)
+	if s := q.Get("from"); s != "" {
+		if from, err = parseTime(s, now, false); err != nil {
+			return
+		}
+	}
+
+	if from.After(to) {
+		err = fmt.Errorf("intervallo non valido: %s è successivo a %s", from.Format(inputLayout), to.Format(inputLayout))
+	}
+	return
+}
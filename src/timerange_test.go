@@ -0,0 +1,108 @@
+package src
+
+import (
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestParseRange(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 30, 0, 0, appLocation)
+	date := func(y int, m time.Month, d, h, min, s int) time.Time {
+		return time.Date(y, m, d, h, min, s, 0, appLocation)
+	}
+
+	tests := []struct {
+		query    string
+		from, to time.Time
+		err      bool
+	}{
+		{"", now.Add(-24 * time.Hour), now, false},
+		{"from=0", time.Unix(0, 0), now, false},
+		{"from=1718000000&to=1718100000", time.Unix(1718000000, 0), time.Unix(1718100000, 0), false},
+		{"from=-7d&to=now", date(2024, 6, 8, 12, 30, 0), now, false},
+		{"from=-1M", date(2024, 5, 15, 12, 30, 0), now, false},
+		{"from=now-2h&to=-1h", date(2024, 6, 15, 10, 30, 0), date(2024, 6, 15, 11, 30, 0), false},
+		{"to=+2h", date(2024, 6, 14, 14, 30, 0), date(2024, 6, 15, 14, 30, 0), false},
+		{"to=now+1d", date(2024, 6, 15, 12, 30, 0), date(2024, 6, 16, 12, 30, 0), false},
+		{"to=%2B2h", date(2024, 6, 14, 14, 30, 0), date(2024, 6, 15, 14, 30, 0), false},
+		{"from=2024-06-01&to=2024-06-10", date(2024, 6, 1, 0, 0, 0), date(2024, 6, 10, 23, 59, 59), false},
+		{"from=2024-06-01T08:00&to=2024-06-01T20:15", date(2024, 6, 1, 8, 0, 0), date(2024, 6, 1, 20, 15, 0), false},
+		{"from=2024-06-01T08:00:00Z", time.Date(2024, 6, 1, 8, 0, 0, 0, time.UTC), now, false},
+		{"to=2024-06-10", date(2024, 6, 9, 23, 59, 59), date(2024, 6, 10, 23, 59, 59), false},
+		{"period=2024", date(2024, 1, 1, 0, 0, 0), date(2024, 12, 31, 23, 59, 59), false},
+		{"period=2024-02", date(2024, 2, 1, 0, 0, 0), date(2024, 2, 29, 23, 59, 59), false},
+		{"period=2024-06-15", date(2024, 6, 15, 0, 0, 0), date(2024, 6, 15, 23, 59, 59), false},
+		{"period=2024-W12", date(2024, 3, 18, 0, 0, 0), date(2024, 3, 24, 23, 59, 59), false},
+		{"period=2021-W01", date(2021, 1, 4, 0, 0, 0), date(2021, 1, 10, 23, 59, 59), false},
+		{"period=2020-W53", date(2020, 12, 28, 0, 0, 0), date(2021, 1, 3, 23, 59, 59), false},
+		{"period=2021-W53", time.Time{}, time.Time{}, true},
+		{"period=2024-W00", time.Time{}, time.Time{}, true},
+		{"period=2024-13", time.Time{}, time.Time{}, true},
+		{"period=2024-06&from=-7d", time.Time{}, time.Time{}, true},
+		{"from=yesterday", time.Time{}, time.Time{}, true},
+		{"from=-7", time.Time{}, time.Time{}, true},
+		{"from=-7x", time.Time{}, time.Time{}, true},
+		{"from=2024-06-10&to=2024-06-01", time.Time{}, time.Time{}, true},
+	}
+	for _, tt := range tests {
+		q, _ := url.ParseQuery(tt.query)
+		from, to, err := parseRange(q, now)
+		if (err != nil) != tt.err {
+			t.Errorf("%s: unexpected error %v", tt.query, err)
+			continue
+		}
+		if !tt.err && (!from.Equal(tt.from) || !to.Equal(tt.to)) {
+			t.Errorf("%s: got %v - %v, want %v - %v", tt.query, from, to, tt.from, tt.to)
+		}
+	}
+}
+
+func TestParseRangeLocation(t *testing.T) {
+	loc, err := time.LoadLocation("Pacific/Auckland")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(l *time.Location) { appLocation = l }(appLocation)
+	appLocation = loc
+
+	q, _ := url.ParseQuery("period=2024-06-15")
+	from, to, err := parseRange(q, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2024, 6, 14, 12, 0, 0, 0, time.UTC); !from.Equal(want) {
+		t.Errorf("got from %v, want %v", from.UTC(), want)
+	}
+	if got := localTime(to.Unix()); got.Day() != 15 || got.Hour() != 23 {
+		t.Errorf("got to %v, want the end of 15 June in Auckland", got)
+	}
+}
+
+func TestRangeParams(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 30, 0, 0, appLocation)
+	tests := []struct {
+		query    string
+		from, to string
+	}{
+		{"", "", ""},
+		{"from=2024-06-01T08:00:00%2B02:00", "1717221600", ""},
+		{"from=-2h&to=now", strconv.FormatInt(now.Add(-2*time.Hour).Unix(), 10), strconv.FormatInt(now.Unix(), 10)},
+		{"period=2024-06-01", "", ""},
+	}
+	day, _ := time.ParseInLocation(time.DateOnly, "2024-06-01", appLocation)
+	tests[3].from, tests[3].to = strconv.FormatInt(day.Unix(), 10), strconv.FormatInt(day.AddDate(0, 0, 1).Unix()-1, 10)
+
+	for _, tt := range tests {
+		q, _ := url.ParseQuery(tt.query)
+		from, to, err := parseRange(q, now)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.query, err)
+		}
+		f, to2 := rangeParams(q, from, to)
+		if f != tt.from || to2 != tt.to {
+			t.Errorf("%q: got %q, %q; want %q, %q", tt.query, f, to2, tt.from, tt.to)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package src
+
+import (
+	"time"
+
+	"gonum.org/v1/plot"
+)
+
+const maxTimeTicks = 10
+
+// timeStep è un passo di calendario per i tick dell'asse temporale.
+type timeStep struct {
+	approx time.Duration // durata indicativa, usata per scegliere il passo
+	hours  int
+	days   int
+	months int
+	format string
+}
+
+var timeSteps = []timeStep{
+	{approx: time.Hour, hours: 1, format: "15:04"},
+	{approx: 2 * time.Hour, hours: 2, format: "15:04"},
+	{approx: 3 * time.Hour, hours: 3, format: "15:04"},
+	{approx: 6 * time.Hour, hours: 6, format: "15:04"},
+	{approx: 12 * time.Hour, hours: 12, format: "15:04"},
+	{approx: 24 * time.Hour, days: 1, format: "Mon 02"},
+	{approx: 48 * time.Hour, days: 2, format: "02 Jan"},
+	{approx: 7 * 24 * time.Hour, days: 7, format: "02 Jan"},
+	{approx: 30 * 24 * time.Hour, months: 1, format: "Jan 2006"},
+	{approx: 61 * 24 * time.Hour, months: 2, format: "Jan 2006"},
+	{approx: 91 * 24 * time.Hour, months: 3, format: "Jan 2006"},
+	{approx: 182 * 24 * time.Hour, months: 6, format: "Jan 2006"},
+	{approx: 365 * 24 * time.Hour, months: 12, format: "2006"},
+	{approx: 2 * 365 * 24 * time.Hour, months: 24, format: "2006"},
+	{approx: 5 * 365 * 24 * time.Hour, months: 60, format: "2006"},
+}
+
+// first restituisce il primo confine del passo non successivo a t, nel fuso di t.
+func (s timeStep) first(t time.Time) time.Time {
+	y, m, d := t.Date()
+	switch {
+	case s.hours > 0:
+		return time.Date(y, m, d, t.Hour()-t.Hour()%s.hours, 0, 0, 0, t.Location())
+	case s.days == 7:
+		// Le settimane iniziano di lunedì
+		return time.Date(y, m, d-(int(t.Weekday())+6)%7, 0, 0, 0, 0, t.Location())
+	case s.days > 0:
+		return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+	}
+	months := y*12 + int(m) - 1
+	months -= months % s.months
+	return time.Date(months/12, time.Month(months%12+1), 1, 0, 0, 0, 0, t.Location())
+}
+
+// next restituisce il confine successivo a t. I passi orari seguono l'ora locale: nei giorni
+// del cambio d'ora le ore che non esistono vengono saltate e quelle ripetute compaiono una volta sola.
+func (s timeStep) next(t time.Time) time.Time {
+	y, m, d := t.Date()
+	switch {
+	case s.hours > 0:
+		h := t.Hour() - t.Hour()%s.hours
+		for {
+			h += s.hours
+			n := time.Date(y, m, d, h, 0, 0, 0, t.Location())
+			if n.After(t) && n.Hour()%s.hours == 0 {
+				return n
+			}
+		}
+	case s.days > 0:
+		return time.Date(y, m, d+s.days, 0, 0, 0, 0, t.Location())
+	}
+	return time.Date(y, m+time.Month(s.months), 1, 0, 0, 0, 0, t.Location())
+}
+
+// timeTicks implementa plot.Ticker scegliendo tick allineati a ore, giorni, settimane, mesi
+// o anni del fuso orario loc, in base all'ampiezza dell'intervallo, con le etichette nella lingua l.
+type timeTicks struct {
+	loc *time.Location
+	l   *Locale
+}
+
+func (tt timeTicks) step(min, max float64) timeStep {
+	span := time.Duration(max-min) * time.Second
+	for _, s := range timeSteps {
+		if span/s.approx < maxTimeTicks {
+			return s
+		}
+	}
+	return timeSteps[len(timeSteps)-1]
+}
+
+func (tt timeTicks) Ticks(min, max float64) (ticks []plot.Tick) {
+	if max <= min {
+		return
+	}
+
+	s := tt.step(min, max)
+	start := time.Unix(int64(min), 0).In(tt.loc)
+	lastDay := -1
+	for t := s.first(start); float64(t.Unix()) <= max; t = s.next(t) {
+		v := float64(t.Unix())
+		if v < min {
+			continue
+		}
+
+		// Con i passi orari il primo tick di ogni giorno riporta anche la data
+		format := s.format
+		if s.hours > 0 && t.YearDay() != lastDay {
+			format = "Mon 02 15:04"
+			lastDay = t.YearDay()
+		}
+		ticks = append(ticks, plot.Tick{Value: v, Label: tt.l.formatTime(t, format)})
+	}
+	return
+}
@@ -0,0 +1,110 @@
+package src
+
+import (
+	"testing"
+	"time"
+)
+
+func tickLabels(t *testing.T, loc *time.Location, from, to time.Time) (labels []string, values []float64) {
+	t.Helper()
+	for _, tick := range (timeTicks{loc: loc, l: newDefaultLocale()}).Ticks(float64(from.Unix()), float64(to.Unix())) {
+		labels = append(labels, tick.Label)
+		values = append(values, tick.Value)
+	}
+	for i := 1; i < len(values); i++ {
+		if values[i] <= values[i-1] {
+			t.Fatalf("ticks are not increasing: %v", labels)
+		}
+	}
+	return
+}
+
+func TestTimeTicksSpringForward(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Rome")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Il 30 marzo 2025 alle 02:00 gli orologi passano alle 03:00
+	from := time.Date(2025, 3, 29, 23, 0, 0, 0, loc)
+	labels, values := tickLabels(t, loc, from, from.Add(8*time.Hour))
+	want := []string{"Sat 29 23:00", "Sun 30 00:00", "01:00", "03:00", "04:00", "05:00", "06:00", "07:00", "08:00"}
+	if len(labels) != len(want) {
+		t.Fatalf("got %v, want %v", labels, want)
+	}
+	for i := range want {
+		if labels[i] != want[i] {
+			t.Errorf("tick %d: got %q, want %q", i, labels[i], want[i])
+		}
+	}
+	if values[3]-values[2] != 3600 {
+		t.Errorf("01:00 and 03:00 should be one hour apart, got %vs", values[3]-values[2])
+	}
+}
+
+func TestTimeTicksFallBack(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Rome")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Il 26 ottobre 2025 alle 03:00 gli orologi tornano alle 02:00: il giorno dura 25 ore
+	from := time.Date(2025, 10, 20, 0, 0, 0, 0, loc)
+	labels, values := tickLabels(t, loc, from, from.AddDate(0, 0, 8))
+	want := []string{"Mon 20", "Tue 21", "Wed 22", "Thu 23", "Fri 24", "Sat 25", "Sun 26", "Mon 27", "Tue 28"}
+	if len(labels) != len(want) {
+		t.Fatalf("got %v, want %v", labels, want)
+	}
+	for i := range want {
+		if labels[i] != want[i] {
+			t.Errorf("tick %d: got %q, want %q", i, labels[i], want[i])
+		}
+	}
+	if values[7]-values[6] != 25*3600 {
+		t.Errorf("26 October should last 25 hours, got %vs", values[7]-values[6])
+	}
+
+	labels, _ = tickLabels(t, loc, time.Date(2025, 10, 26, 0, 0, 0, 0, loc), time.Date(2025, 10, 26, 6, 0, 0, 0, loc))
+	if len(labels) != 7 {
+		t.Errorf("the repeated hour should appear once, got %v", labels)
+	}
+}
+
+func TestTimeTicksRanges(t *testing.T) {
+	loc := time.UTC
+	from := time.Date(2025, 1, 15, 0, 0, 0, 0, loc)
+
+	tests := []struct {
+		to    time.Time
+		first string
+	}{
+		{from.AddDate(0, 0, 1), "Wed 15 00:00"},
+		{from.AddDate(0, 0, 6), "Wed 15"},
+		{from.AddDate(0, 1, 0), "20 Jan"},
+		{from.AddDate(1, 0, 0), "Mar 2025"},
+		{from.AddDate(10, 0, 0), "2026"},
+	}
+	for _, tt := range tests {
+		labels, _ := tickLabels(t, loc, from, tt.to)
+		if len(labels) == 0 || len(labels) > maxTimeTicks {
+			t.Errorf("range to %s: got %d ticks", tt.to.Format(time.DateOnly), len(labels))
+			continue
+		}
+		if labels[0] != tt.first {
+			t.Errorf("range to %s: first tick %q, want %q", tt.to.Format(time.DateOnly), labels[0], tt.first)
+		}
+	}
+}
+
+func TestRelativeTimeTicks(t *testing.T) {
+	start := time.Date(2015, 3, 1, 12, 0, 0, 0, time.UTC)
+	end := start.AddDate(10, 0, 0)
+
+	ticks := relativeTimeTicks{start: start, l: newDefaultLocale()}.Ticks(0, end.Sub(start).Seconds())
+	if len(ticks) == 0 || len(ticks) > maxTimeTicks {
+		t.Fatalf("got %d ticks over ten years", len(ticks))
+	}
+	if ticks[0].Label != "2016" || ticks[0].Value != time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC).Sub(start).Seconds() {
+		t.Errorf("first tick %q at %v", ticks[0].Label, ticks[0].Value)
+	}
+}
@@ -0,0 +1,313 @@
+package src
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Grandezze fisiche delle misure, che determinano le unità disponibili
+const (
+	quantityTemperature   = "temperature"
+	quantitySpeed         = "speed"
+	quantityPressure      = "pressure"
+	quantityPrecipitation = "precipitation" // quantità, es. mm
+	quantityRate          = "rate"          // intensità, es. mm/h
+	quantityDistance      = "distance"
+
+	unitsCookie = "units"
+	unitsCustom = "custom"
+)
+
+// unitDef descrive un'unità di misura e la conversione dall'unità canonica salvata nel database.
+type unitDef struct {
+	Symbol    string
+	Precision int
+	Convert   func(float64) float64
+}
+
+func scale(k float64) func(float64) float64 {
+	return func(v float64) float64 { return v * k }
+}
+
+// beaufort converte una velocità in m/s nel grado corrispondente della scala Beaufort.
+func beaufort(v float64) float64 {
+	return min(math.Pow(max(v, 0)/0.836, 2.0/3), 12)
+}
+
+// unitOption associa un'unità alla chiave usata nei parametri e nel cookie.
+type unitOption struct {
+	Key string
+	unitDef
+}
+
+// unitOptions elenca, per ogni grandezza, le unità disponibili; la prima è quella canonica.
+var unitOptions = map[string][]unitOption{
+	quantityTemperature: {
+		{"C", unitDef{"°C", 1, scale(1)}},
+		{"F", unitDef{"°F", 1, func(v float64) float64 { return v*9/5 + 32 }}},
+	},
+	quantitySpeed: {
+		{"ms", unitDef{"m/s", 1, scale(1)}},
+		{"kmh", unitDef{"km/h", 1, scale(3.6)}},
+		{"mph", unitDef{"mph", 1, scale(3600 / 1609.344)}},
+		{"kn", unitDef{"kn", 1, scale(3600 / 1852.0)}},
+		{"bft", unitDef{"Bft", 0, beaufort}},
+	},
+	quantityPressure: {
+		{"hPa", unitDef{"hPa", 0, scale(1)}},
+		{"inHg", unitDef{"inHg", 2, scale(1 / 33.8639)}},
+		{"mmHg", unitDef{"mmHg", 0, scale(1 / 1.33322)}},
+	},
+	quantityPrecipitation: {
+		{"mm", unitDef{"mm", 1, scale(1)}},
+		{"in", unitDef{"in", 2, scale(1 / 25.4)}},
+	},
+	quantityRate: {
+		{"mm", unitDef{"mm/h", 2, scale(1)}},
+		{"in", unitDef{"in/h", 3, scale(1 / 25.4)}},
+	},
+	quantityDistance: {
+		{"m", unitDef{"m", 0, scale(1)}},
+		{"km", unitDef{"km", 1, scale(0.001)}},
+		{"mi", unitDef{"mi", 1, scale(1 / 1609.344)}},
+	},
+}
+
+// UnitSystem indica l'unità scelta per ogni grandezza.
+type UnitSystem struct {
+	Name          string `json:"name"`
+	Temperature   string `json:"temperature"`
+	Speed         string `json:"speed"`
+	Pressure      string `json:"pressure"`
+	Precipitation string `json:"precipitation"`
+	Distance      string `json:"distance"`
+}
+
+var unitSystems = map[string]UnitSystem{
+	"metric":   {Name: "metric", Temperature: "C", Speed: "ms", Pressure: "hPa", Precipitation: "mm", Distance: "m"},
+	"imperial": {Name: "imperial", Temperature: "F", Speed: "mph", Pressure: "inHg", Precipitation: "in", Distance: "mi"},
+}
+
+// setting restituisce l'unità scelta per una grandezza.
+func (us *UnitSystem) setting(quantity string) *string {
+	switch quantity {
+	case quantityTemperature:
+		return &us.Temperature
+	case quantitySpeed:
+		return &us.Speed
+	case quantityPressure:
+		return &us.Pressure
+	case quantityPrecipitation, quantityRate:
+		return &us.Precipitation
+	case quantityDistance:
+		return &us.Distance
+	}
+	return nil
+}
+
+func (us UnitSystem) unit(quantity string) (unitDef, bool) {
+	s := us.setting(quantity)
+	if s == nil {
+		return unitDef{}, false
+	}
+	defs := unitOptions[quantity]
+	i := slices.IndexFunc(defs, func(o unitOption) bool { return o.Key == *s })
+	if i < 0 {
+		return defs[0].unitDef, true
+	}
+	return defs[i].unitDef, true
+}
+
+// String codifica il sistema di unità per il cookie e le chiavi delle cache.
+func (us UnitSystem) String() string {
+	if us.Name != unitsCustom {
+		return us.Name
+	}
+	return unitsCustom + ":" + strings.Join([]string{us.Temperature, us.Speed, us.Pressure, us.Precipitation, us.Distance}, ",")
+}
+
+// canonical indica se il sistema usa le stesse unità del database.
+func (us UnitSystem) canonical() bool {
+	m := unitSystems["metric"]
+	m.Name = us.Name
+	return us == m
+}
+
+// Info restituisce i metadati di una misura con l'unità e la precisione del sistema scelto.
+func (us UnitSystem) Info(measure string) MeasureInfo {
+	info := getMeasureInfo(measure)
+	if u, ok := us.unit(info.Quantity); ok {
+		info.Unit, info.Precision = u.Symbol, u.Precision
+	}
+	return info
+}
+
+func (us UnitSystem) Label(measure string) string {
+	return us.Info(measure).Label()
+}
+
+func (us UnitSystem) Unit(measure string) string {
+	return us.Info(measure).Unit
+}
+
+// Convert converte un valore di una misura dall'unità canonica a quella del sistema.
+func (us UnitSystem) Convert(measure string, v float64) float64 {
+	if u, ok := us.unit(getMeasureInfo(measure).Quantity); ok {
+		return u.Convert(v)
+	}
+	return v
+}
+
+// ConvertDelta converte una differenza tra due valori di una misura, ignorando l'eventuale offset dell'unità.
+// Vale solo per le conversioni lineari, come quelle di temperatura: per le altre (es. la scala Beaufort)
+// si devono convertire i due valori e sottrarli.
+func (us UnitSystem) ConvertDelta(measure string, v float64) float64 {
+	return us.Convert(measure, v) - us.Convert(measure, 0)
+}
+
+// toFloat converte i valori numerici dei record, per l'uso nei template.
+func toFloat(v any) float64 {
+	switch v := v.(type) {
+	case float64:
+		return v
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	}
+	return 0
+}
+
+// dataPoints restituisce i punti delle misure richieste convertiti nelle unità del sistema.
+// I punti in cache non vengono modificati.
+func (us UnitSystem) dataPoints(measures []string, f, t *int64) ([]DataPoint, error) {
+	dp, err := getDataPoints(measures, f, t)
+	if err != nil || us.canonical() {
+		return dp, err
+	}
+
+	converted := make([]DataPoint, len(dp))
+	for i := range dp {
+		converted[i].Dt = dp[i].Dt
+		for j, m := range measures {
+			converted[i].setValue(j, us.Convert(m, dp[i].value(j)))
+		}
+	}
+	return converted, nil
+}
+
+// convertJSON converte le misure di una risposta JSON (record o liste di record), usando i nomi
+// delle colonne come chiavi. Passare per JSON evita di arrotondare i campi interi come la visibilità.
+func (us UnitSystem) convertJSON(b []byte) ([]byte, error) {
+	if us.canonical() {
+		return b, nil
+	}
+
+	convert := func(r map[string]any) {
+		for k, v := range r {
+			if f, ok := v.(float64); ok && getMeasureInfo(k).Quantity != "" {
+				r[k] = us.Convert(k, f)
+			}
+		}
+	}
+
+	if len(b) > 0 && b[0] == '[' {
+		var records []map[string]any
+		if err := json.Unmarshal(b, &records); err != nil {
+			return nil, err
+		}
+		for _, r := range records {
+			convert(r)
+		}
+		return json.Marshal(records)
+	}
+
+	var record map[string]any
+	if err := json.Unmarshal(b, &record); err != nil {
+		return nil, err
+	}
+	convert(record)
+	return json.Marshal(record)
+}
+
+// parseUnitSystem interpreta il nome di un sistema di unità o la codifica di String.
+func parseUnitSystem(s string) (us UnitSystem, err error) {
+	if us, ok := unitSystems[s]; ok {
+		return us, nil
+	}
+
+	name, values, ok := strings.Cut(s, ":")
+	parts := strings.Split(values, ",")
+	if !ok || name != unitsCustom || len(parts) != 5 {
+		err = errors.New("sistema di unità non valido: " + s)
+		return
+	}
+
+	us = UnitSystem{Name: unitsCustom, Temperature: parts[0], Speed: parts[1], Pressure: parts[2], Precipitation: parts[3], Distance: parts[4]}
+	return us, us.validate()
+}
+
+func (us UnitSystem) validate() error {
+	for _, q := range []string{quantityTemperature, quantitySpeed, quantityPressure, quantityPrecipitation, quantityDistance} {
+		s := *us.setting(q)
+		if !slices.ContainsFunc(unitOptions[q], func(o unitOption) bool { return o.Key == s }) {
+			return fmt.Errorf("unità non valida per %s: %s", q, s)
+		}
+	}
+	return nil
+}
+
+// getUnits legge il sistema di unità dal parametro units (metric, imperial o custom, con le
+// unità nei parametri temp_unit, wind_unit, pressure_unit, precip_unit e visibility_unit,
+// oppure la codifica di String), o dal cookie impostato dalle pagine. Il sistema predefinito è quello metrico.
+func getUnits(r *http.Request) (UnitSystem, error) {
+	q := r.URL.Query()
+	name := q.Get("units")
+	if name == "" {
+		if c, err := r.Cookie(unitsCookie); err == nil {
+			if us, err := parseUnitSystem(c.Value); err == nil {
+				return us, nil
+			}
+		}
+		return unitSystems["metric"], nil
+	}
+
+	if name != unitsCustom {
+		return parseUnitSystem(name)
+	}
+
+	us := unitSystems["metric"]
+	us.Name = unitsCustom
+	for param, quantity := range map[string]string{
+		"temp_unit":       quantityTemperature,
+		"wind_unit":       quantitySpeed,
+		"pressure_unit":   quantityPressure,
+		"precip_unit":     quantityPrecipitation,
+		"visibility_unit": quantityDistance,
+	} {
+		if v := q.Get(param); v != "" {
+			*us.setting(quantity) = v
+		}
+	}
+	return us, us.validate()
+}
+
+// setUnitsCookie salva il sistema di unità scelto esplicitamente, così che le pagine successive lo ricordino.
+func setUnitsCookie(w http.ResponseWriter, r *http.Request, us UnitSystem) {
+	if r.URL.Query().Get("units") == "" {
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     unitsCookie,
+		Value:    us.String(),
+		Path:     "/",
+		Expires:  time.Now().AddDate(1, 0, 0),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
@@ -0,0 +1,100 @@
+package src
+
+import (
+	"math"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestUnitConversions(t *testing.T) {
+	custom := UnitSystem{Name: unitsCustom, Temperature: "F", Speed: "kmh", Pressure: "mmHg", Precipitation: "in", Distance: "km"}
+	tests := []struct {
+		us      UnitSystem
+		measure string
+		value   float64
+		want    float64
+	}{
+		{unitSystems["metric"], "temp", 21.5, 21.5},
+		{unitSystems["imperial"], "temp", 100, 212},
+		{unitSystems["imperial"], "dew_point", -40, -40},
+		{unitSystems["imperial"], "wind_speed", 1609.344 / 3600, 1},
+		{unitSystems["imperial"], "sea_level", 1013.25, 29.921},
+		{unitSystems["imperial"], "rain_1h", 25.4, 1},
+		{unitSystems["imperial"], "humidity", 55, 55},
+		{custom, "wind_speed", 10, 36},
+		{custom, "pressure", 1013.25, 760},
+		{custom, "visibility", 10000, 10},
+	}
+
+	for _, tt := range tests {
+		if got := tt.us.Convert(tt.measure, tt.value); math.Abs(got-tt.want) > 0.01 {
+			t.Errorf("%s %s %g: got %g, want %g", tt.us, tt.measure, tt.value, got, tt.want)
+		}
+	}
+
+	if got := unitSystems["imperial"].ConvertDelta("temp", 10); got != 18 {
+		t.Errorf("delta: got %g, want 18", got)
+	}
+}
+
+func TestBeaufort(t *testing.T) {
+	tests := []struct {
+		speed float64
+		want  float64
+	}{
+		{0, 0},
+		{0.3, 1},
+		{3.4, 3},
+		{10.8, 6},
+		{32.7, 12},
+		{50, 12},
+	}
+
+	for _, tt := range tests {
+		if got := math.Round(beaufort(tt.speed)); got != tt.want {
+			t.Errorf("%g m/s: got %g Bft, want %g", tt.speed, got, tt.want)
+		}
+	}
+}
+
+func TestGetUnits(t *testing.T) {
+	tests := []struct {
+		query  string
+		cookie string
+		want   string
+		err    bool
+	}{
+		{"", "", "metric", false},
+		{"units=imperial", "", "imperial", false},
+		{"", "imperial", "imperial", false},
+		{"", "bogus", "metric", false},
+		{"units=metric", "imperial", "metric", false},
+		{"units=custom&temp_unit=F&wind_unit=bft", "", "custom:F,bft,hPa,mm,m", false},
+		{"units=custom:C,kn,inHg,mm,km", "", "custom:C,kn,inHg,mm,km", false},
+		{"", "custom:F,mph,hPa,in,m", "custom:F,mph,hPa,in,m", false},
+		{"units=kelvin", "", "", true},
+		{"units=custom&wind_unit=furlong", "", "", true},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/api/latest?"+tt.query, nil)
+		if tt.cookie != "" {
+			r.Header.Set("Cookie", unitsCookie+"="+tt.cookie)
+		}
+
+		us, err := getUnits(r)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%q: expected an error, got %s", tt.query, us)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.query, err)
+			continue
+		}
+		if us.String() != tt.want {
+			t.Errorf("%q (cookie %q): got %s, want %s", tt.query, tt.cookie, us, tt.want)
+		}
+	}
+}
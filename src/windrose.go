@@ -0,0 +1,190 @@
+package src
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"math"
+	"slices"
+	"strconv"
+
+	bh "github.com/birabittoh/bunnyhue"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/text"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+const (
+	roseSectors   = 16
+	roseArcPoints = 8
+	calmSpeed     = 0.5
+)
+
+var (
+	// Limiti superiori delle classi di velocità del vento, in m/s
+	roseSpeedBins = []float64{2, 4, 6, 8, math.Inf(1)}
+	roseLabels    = []string{"N", "E", "S", "W"}
+)
+
+// windRose implementa plot.Plotter disegnando una rosa dei venti: per ogni settore di direzione
+// le classi di velocità sono impilate radialmente, con raggio proporzionale alla frequenza.
+type windRose struct {
+	freq   [roseSectors][]float64 // frequenza (%) per settore e classe di velocità
+	calm   float64
+	labels []string // punti cardinali, da nord in senso orario
+	colors []color.Color
+	line   color.Color
+	text   text.Style
+}
+
+// computeWindRose calcola le frequenze per settore e classe a partire da direzione (Value0) e velocità (Value1).
+// I campioni con direzione negativa, cioè non disponibile, vengono ignorati.
+func computeWindRose(dp []DataPoint) (freq [roseSectors][]float64, calm float64) {
+	for i := range freq {
+		freq[i] = make([]float64, len(roseSpeedBins))
+	}
+	dp = slices.DeleteFunc(slices.Clone(dp), func(p DataPoint) bool { return p.Value0 < 0 })
+	if len(dp) == 0 {
+		return
+	}
+
+	unit := 100 / float64(len(dp))
+	width := 360.0 / roseSectors
+	for _, p := range dp {
+		if p.Value1 < calmSpeed {
+			calm += unit
+			continue
+		}
+
+		sector := int(math.Mod(p.Value0+width/2, 360)/width) % roseSectors
+		for b, limit := range roseSpeedBins {
+			if p.Value1 < limit {
+				freq[sector][b] += unit
+				break
+			}
+		}
+	}
+	return
+}
+
+// maxFrequency restituisce la frequenza del settore più frequente, arrotondata per eccesso a un multiplo di 5.
+func (wr *windRose) maxFrequency() float64 {
+	var m float64
+	for _, bins := range wr.freq {
+		var total float64
+		for _, f := range bins {
+			total += f
+		}
+		m = max(m, total)
+	}
+	return max(math.Ceil(m/5)*5, 5)
+}
+
+func (wr *windRose) Plot(c draw.Canvas, plt *plot.Plot) {
+	pad := vg.Points(14)
+	radius := min(c.Max.X-c.Min.X, c.Max.Y-c.Min.Y)/2 - pad
+	center := vg.Point{X: c.Min.X + pad + radius, Y: (c.Min.Y + c.Max.Y) / 2}
+	scale := radius / vg.Length(wr.maxFrequency())
+
+	point := func(deg float64, r vg.Length) vg.Point {
+		a := deg * math.Pi / 180
+		return vg.Point{X: center.X + r*vg.Length(math.Sin(a)), Y: center.Y + r*vg.Length(math.Cos(a))}
+	}
+	arc := func(from, to float64, r vg.Length) (pts []vg.Point) {
+		n := int(math.Ceil((to - from) / 360 * roseSectors * roseArcPoints))
+		for i := 0; i <= n; i++ {
+			pts = append(pts, point(from+(to-from)*float64(i)/float64(n), r))
+		}
+		return
+	}
+
+	// Settori
+	width := 360.0 / roseSectors
+	for s, bins := range wr.freq {
+		from, to := float64(s)*width-width/2, float64(s)*width+width/2
+		var inner float64
+		for b, f := range bins {
+			if f == 0 {
+				continue
+			}
+			outer := inner + f
+			poly := arc(from, to, vg.Length(outer)*scale)
+			inArc := arc(from, to, vg.Length(inner)*scale)
+			for i := len(inArc) - 1; i >= 0; i-- {
+				poly = append(poly, inArc[i])
+			}
+			c.FillPolygon(wr.colors[b], poly)
+			inner = outer
+		}
+	}
+
+	// Griglia e etichette
+	grid := draw.LineStyle{Color: wr.line, Width: vg.Points(0.5), Dashes: []vg.Length{vg.Points(2), vg.Points(2)}}
+	step := wr.maxFrequency() / 4
+	for i := 1; i <= 4; i++ {
+		r := vg.Length(step*float64(i)) * scale
+		c.StrokeLines(grid, arc(0, 360, r))
+		label := wr.text
+		label.XAlign, label.YAlign = draw.XLeft, draw.YBottom
+		c.FillText(label, point(45, r), strconv.FormatFloat(step*float64(i), 'f', -1, 64)+"%")
+	}
+	for i, l := range wr.labels {
+		deg := float64(i) * 90
+		c.StrokeLine2(grid, center.X, center.Y, point(deg, radius).X, point(deg, radius).Y)
+		label := wr.text
+		label.XAlign, label.YAlign = draw.XCenter, draw.YCenter
+		c.FillText(label, point(deg, radius+pad/2), l)
+	}
+}
+
+// legendThumb disegna un quadrato pieno nella legenda.
+type legendThumb struct {
+	color color.Color
+}
+
+func (lt legendThumb) Thumbnail(c *draw.Canvas) {
+	c.FillPolygon(lt.color, []vg.Point{{X: c.Min.X, Y: c.Min.Y}, {X: c.Min.X, Y: c.Max.Y}, {X: c.Max.X, Y: c.Max.Y}, {X: c.Max.X, Y: c.Min.Y}})
+}
+
+func plotWindRose(f, t *int64, us UnitSystem, l *Locale, palette *bh.Palette) (p *plot.Plot, err error) {
+	dp, err := getDataPoints([]string{"wind_deg", "wind_speed"}, f, t)
+	if err != nil {
+		err = errors.New("errore nella lettura dei dati: " + err.Error())
+		return
+	}
+
+	p = newPlot(l, palette)
+	p.HideAxes()
+
+	wr := &windRose{
+		colors: []color.Color{palette.Blue, palette.Cyan, palette.Green, palette.Orange, palette.Red},
+		line:   palette.Primary,
+		text:   p.Legend.TextStyle,
+	}
+	for _, name := range roseLabels {
+		wr.labels = append(wr.labels, l.T(name))
+	}
+	wr.freq, wr.calm = computeWindRose(dp)
+	p.Add(wr)
+
+	p.Legend.Top = true
+	// Gli estremi degli intervalli sono in m/s, convertiti e arrotondati per la legenda
+	info := us.Info("wind_speed")
+	speed := func(v float64) string {
+		k := math.Pow(10, float64(info.Precision))
+		return strconv.FormatFloat(math.Round(us.Convert("wind_speed", v)*k)/k, 'f', -1, 64)
+	}
+	lower := 0.0
+	for b, upper := range roseSpeedBins {
+		label := fmt.Sprintf("%s-%s %s", speed(lower), speed(upper), info.Unit)
+		if math.IsInf(upper, 1) {
+			label = fmt.Sprintf(">%s %s", speed(lower), info.Unit)
+		}
+		p.Legend.Add(label, legendThumb{wr.colors[b]})
+		lower = upper
+	}
+	p.Legend.Add(l.T("Calm %s%%", l.Float(wr.calm, 1)))
+
+	return
+}
@@ -0,0 +1,37 @@
+package src
+
+import (
+	"math"
+	"testing"
+)
+
+func TestComputeWindRose(t *testing.T) {
+	dp := []DataPoint{
+		{Value0: 0, Value1: 3},
+		{Value0: 350, Value1: 3},
+		{Value0: 90, Value1: 10},
+		{Value0: 180, Value1: 0.2},
+		{Value0: -40, Value1: 5}, // direzione non disponibile
+	}
+
+	freq, calm := computeWindRose(dp)
+	if calm != 25 {
+		t.Errorf("calm = %v, want 25", calm)
+	}
+	if freq[0][1] != 50 {
+		t.Errorf("north 2-4 m/s = %v, want 50", freq[0][1])
+	}
+	if freq[4][len(roseSpeedBins)-1] != 25 {
+		t.Errorf("east above 8 m/s = %v, want 25", freq[4][len(roseSpeedBins)-1])
+	}
+
+	var total float64
+	for _, bins := range freq {
+		for _, f := range bins {
+			total += f
+		}
+	}
+	if math.Abs(total+calm-100) > 1e-9 {
+		t.Errorf("frequencies add up to %v", total+calm)
+	}
+}